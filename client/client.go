@@ -0,0 +1,341 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client is a client of the core API and the warcserver web API of gowarcserver.
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+)
+
+// Client is a client of a gowarcserver.
+type Client struct {
+	baseUrl    string
+	httpClient *http.Client
+}
+
+type Options struct {
+	HttpClient *http.Client
+}
+
+type Option func(opts *Options)
+
+// WithHttpClient sets the http client used to send requests, defaults to http.DefaultClient.
+func WithHttpClient(c *http.Client) Option {
+	return func(opts *Options) {
+		opts.HttpClient = c
+	}
+}
+
+// New returns a client of the server at baseUrl, including any path prefix of the server.
+func New(baseUrl string, options ...Option) (*Client, error) {
+	opts := &Options{
+		HttpClient: http.DefaultClient,
+	}
+	for _, apply := range options {
+		apply(opts)
+	}
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base url, missing scheme or host: %s", baseUrl)
+	}
+	return &Client{
+		baseUrl:    strings.TrimSuffix(u.String(), "/"),
+		httpClient: opts.HttpClient,
+	}, nil
+}
+
+// SearchRequest is a search of the cdx index. The fields are the parameters of the cdx API.
+type SearchRequest struct {
+	// Url to search for, may contain wildcards.
+	Url string
+	// MatchType is one of "exact", "prefix", "host", "domain" or "verbatim".
+	MatchType string
+	// Sort is "closest" or "reverse", or empty for ascending key order.
+	Sort string
+	// Closest is the timestamp to sort closest to.
+	Closest string
+	// From is the start of the date range.
+	From string
+	// To is the end of the date range.
+	To string
+	// Filter are the filters of the results.
+	Filter []string
+	// Limit is the maximum number of results.
+	Limit int
+	// Collapse is the field to collapse results on.
+	Collapse string
+	// ResumeKey is the key to resume a previous search from.
+	ResumeKey string
+}
+
+// values returns the parameters of the cdx API of the request.
+func (r SearchRequest) values() url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set(api.ParamUrl, r.Url)
+	set(api.ParamMatchType, r.MatchType)
+	set(api.ParamSort, r.Sort)
+	set(api.ParamClosest, r.Closest)
+	set(api.ParamFrom, r.From)
+	set(api.ParamTo, r.To)
+	for _, filter := range r.Filter {
+		values.Add(api.ParamFilter, filter)
+	}
+	if r.Limit > 0 {
+		values.Set(api.ParamLimit, strconv.Itoa(r.Limit))
+	}
+	set(api.ParamCollapse, r.Collapse)
+	set(api.ParamResumeKey, r.ResumeKey)
+	return values
+}
+
+// limitValues returns the parameters of a list limited to limit items, or unlimited if limit is 0.
+func limitValues(limit int) url.Values {
+	values := url.Values{}
+	if limit > 0 {
+		values.Set(api.ParamLimit, strconv.Itoa(limit))
+	}
+	return values
+}
+
+// endpoint returns the url of the endpoint with path elements elem and query values.
+func (c *Client) endpoint(values url.Values, elem ...string) string {
+	u := c.baseUrl
+	for _, e := range elem {
+		u += "/" + url.PathEscape(e)
+	}
+	if len(values) > 0 {
+		u += "?" + values.Encode()
+	}
+	return u
+}
+
+// do sends a request and returns the response if its status is successful, else the error of the response.
+func (c *Client) do(ctx context.Context, method string, u string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, responseError(res)
+	}
+	return res, nil
+}
+
+func (c *Client) get(ctx context.Context, u string) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, u, nil, "")
+}
+
+// Search searches the cdx index and returns an iterator over the results.
+func (c *Client) Search(ctx context.Context, req SearchRequest) (*CdxIterator, error) {
+	values := req.values()
+	values.Set(api.ParamOutput, api.OutputJson)
+	res, err := c.get(ctx, c.endpoint(values, "cdx"))
+	if err != nil {
+		return nil, err
+	}
+	return &CdxIterator{stream: newStream(res)}, nil
+}
+
+// Records searches the cdx index and returns an iterator over the records of the results.
+func (c *Client) Records(ctx context.Context, req SearchRequest) (*RecordIterator, error) {
+	it, err := c.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordIterator{ctx: ctx, client: c, cdx: it}, nil
+}
+
+// GetStorageRef returns the storage ref of the record with id.
+func (c *Client) GetStorageRef(ctx context.Context, id string) (string, error) {
+	res, err := c.get(ctx, c.endpoint(nil, "id", id))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	ref := strings.TrimSpace(string(b))
+	if ref == "" {
+		return "", &ResponseError{StatusCode: http.StatusNotFound, Message: "storage ref not found: " + id}
+	}
+	return ref, nil
+}
+
+// ListStorageRefs returns an iterator over at most limit storage refs of the id index, or all if limit is 0.
+func (c *Client) ListStorageRefs(ctx context.Context, limit int) (*StorageRefIterator, error) {
+	res, err := c.get(ctx, c.endpoint(limitValues(limit), "id"))
+	if err != nil {
+		return nil, err
+	}
+	return &StorageRefIterator{stream: newStream(res)}, nil
+}
+
+// GetFileInfo returns the file info of the file with filename.
+func (c *Client) GetFileInfo(ctx context.Context, filename string) (*schema.FileInfo, error) {
+	res, err := c.get(ctx, c.endpoint(nil, append([]string{"file"}, strings.Split(filename, "/")...)...))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo := new(schema.FileInfo)
+	if err := unmarshalOptions.Unmarshal(b, fileInfo); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file info: %w", err)
+	}
+	if fileInfo.GetName() == "" {
+		return nil, &ResponseError{StatusCode: http.StatusNotFound, Message: "file info not found: " + filename}
+	}
+	return fileInfo, nil
+}
+
+// ListFileInfo returns an iterator over at most limit file infos of the file index, or all if limit is 0.
+func (c *Client) ListFileInfo(ctx context.Context, limit int) (*FileInfoIterator, error) {
+	res, err := c.get(ctx, c.endpoint(limitValues(limit), "file"))
+	if err != nil {
+		return nil, err
+	}
+	return &FileInfoIterator{stream: newStream(res)}, nil
+}
+
+// record is a record that closes the response it is read from when it is closed.
+type record struct {
+	gowarc.WarcRecord
+	body io.Closer
+}
+
+func (r record) Close() error {
+	err := r.WarcRecord.Close()
+	if closeErr := r.body.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Record returns the record with id. The record must be closed.
+func (c *Client) Record(ctx context.Context, id string) (gowarc.WarcRecord, error) {
+	res, err := c.get(ctx, c.endpoint(nil, "record", id))
+	if err != nil {
+		return nil, err
+	}
+	rec, _, _, err := gowarc.NewUnmarshaler().Unmarshal(bufio.NewReader(res.Body))
+	if err != nil {
+		if rec != nil {
+			_ = rec.Close()
+		}
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("failed to unmarshal record: %w", err)
+	}
+	return record{WarcRecord: rec, body: res.Body}, nil
+}
+
+// Resource returns the archived response of uri captured closest to timestamp,
+// as served by the warcserver web API. The body of the response must be closed.
+func (c *Client) Resource(ctx context.Context, timestamp string, uri string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+"/warcserver/web/"+timestamp+"id_/"+uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// archived responses have a Memento-Datetime header, errors of the server have not
+	if res.Header.Get("Memento-Datetime") == "" {
+		return nil, responseError(res)
+	}
+	return res, nil
+}
+
+// CreateReport starts generating a report of the results of a search.
+func (c *Client) CreateReport(ctx context.Context, req SearchRequest) (*schema.Report, error) {
+	res, err := c.do(ctx, http.MethodPost, c.endpoint(nil, "report"), strings.NewReader(req.values().Encode()), "application/x-www-form-urlencoded")
+	if err != nil {
+		return nil, err
+	}
+	return readReport(res)
+}
+
+// GetReport returns the report with id.
+func (c *Client) GetReport(ctx context.Context, id string) (*schema.Report, error) {
+	res, err := c.get(ctx, c.endpoint(nil, "report", id))
+	if err != nil {
+		return nil, err
+	}
+	return readReport(res)
+}
+
+func readReport(res *http.Response) (*schema.Report, error) {
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	report := new(schema.Report)
+	if err := unmarshalOptions.Unmarshal(b, report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %w", err)
+	}
+	return report, nil
+}
+
+// ListReports returns an iterator over at most limit reports, or all if limit is 0.
+func (c *Client) ListReports(ctx context.Context, limit int) (*ReportIterator, error) {
+	res, err := c.get(ctx, c.endpoint(limitValues(limit), "report"))
+	if err != nil {
+		return nil, err
+	}
+	return &ReportIterator{stream: newStream(res)}, nil
+}
+
+// DeleteReport deletes the report with id.
+func (c *Client) DeleteReport(ctx context.Context, id string) error {
+	res, err := c.do(ctx, http.MethodDelete, c.endpoint(nil, "report", id), nil, "")
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
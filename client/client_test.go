@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/client/clienttest"
+	"github.com/nlnwa/gowarcserver/server/api"
+)
+
+const exampleWarc = "../testdata/example.warc.gz"
+
+func newClient(t *testing.T, options ...clienttest.Option) *Client {
+	server := clienttest.NewServer(t, append([]clienttest.Option{clienttest.WithFiles(exampleWarc)}, options...)...)
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSearch(t *testing.T) {
+	c := newClient(t, clienttest.WithLimits(api.QueryLimits{"cdx": {MaxKeys: 1}}))
+
+	tests := []struct {
+		name      string
+		req       SearchRequest
+		results   int
+		err       error
+		truncated string
+		resume    bool
+	}{
+		{name: "exact", req: SearchRequest{Url: "http://example.com/", Limit: 1}, results: 1, resume: true},
+		{name: "max keys", req: SearchRequest{Url: "http://example.com/"}, results: 1, truncated: api.TruncatedMaxKeys, resume: true},
+		{name: "invalid", req: SearchRequest{Url: "http://example.com/", MatchType: "nope"}, err: BadRequestError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := c.Search(context.Background(), tt.req)
+			if err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			defer it.Close()
+
+			results := 0
+			for it.Next() {
+				if it.Cdx().GetUri() != tt.req.Url {
+					t.Errorf("got uri %s, want %s", it.Cdx().GetUri(), tt.req.Url)
+				}
+				results++
+			}
+			if !errors.Is(it.Err(), tt.err) {
+				t.Errorf("got error %v, want %v", it.Err(), tt.err)
+			}
+			if results != tt.results {
+				t.Errorf("got %d results, want %d", results, tt.results)
+			}
+			if it.Truncated() != tt.truncated {
+				t.Errorf("got truncated %q, want %q", it.Truncated(), tt.truncated)
+			}
+			if (it.ResumeKey() != "") != tt.resume {
+				t.Errorf("got resume key %q, want resume key %v", it.ResumeKey(), tt.resume)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	c := newClient(t)
+
+	var timestamps []string
+	req := SearchRequest{Url: "http://example.com/", Limit: 1}
+	for i := 0; i < 3; i++ {
+		it, err := c.Search(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for it.Next() {
+			timestamps = append(timestamps, it.Cdx().GetSts().AsTime().String())
+		}
+		if it.Err() != nil {
+			t.Fatal(it.Err())
+		}
+		if it.ResumeKey() == "" {
+			break
+		}
+		req.ResumeKey = it.ResumeKey()
+	}
+	// the response and the revisit record of example.com
+	if len(timestamps) != 2 || timestamps[0] == timestamps[1] {
+		t.Errorf("got timestamps %v, want two distinct timestamps", timestamps)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	c := newClient(t)
+
+	it, err := c.Records(context.Background(), SearchRequest{Url: "http://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var types []gowarc.RecordType
+	for it.Next() {
+		if got, want := it.Record().WarcHeader().GetId(gowarc.WarcRecordID), it.Cdx().GetRid(); got != want {
+			t.Errorf("got record %s, want %s", got, want)
+		}
+		types = append(types, it.Record().Type())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	// the revisit record is merged with the response record it refers to
+	if len(types) != 2 || types[0] != gowarc.Response || types[1] != gowarc.Response {
+		t.Errorf("got record types %v, want two responses", types)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	fileInfo, err := c.GetFileInfo(ctx, "example.warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.GetSize() == 0 {
+		t.Errorf("got size 0 of %s", fileInfo.GetName())
+	}
+
+	it, err := c.ListFileInfo(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 1 {
+		t.Errorf("got %d file infos and error %v, want 1 file info", count, it.Err())
+	}
+
+	ref, err := c.GetStorageRef(ctx, "urn:uuid:a9c51e3e-0221-11e7-bf66-0242ac120005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "warcfile:example.warc.gz#784"; ref != want {
+		t.Errorf("got storage ref %s, want %s", ref, want)
+	}
+
+	refs, err := c.ListStorageRefs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count = 0
+	for refs.Next() {
+		if refs.StorageRef().Filename != "example.warc.gz" {
+			t.Errorf("got filename %s, want example.warc.gz", refs.StorageRef().Filename)
+		}
+		count++
+	}
+	if refs.Err() != nil || count != 1 {
+		t.Errorf("got %d storage refs and error %v, want 1 storage ref", count, refs.Err())
+	}
+}
+
+func TestResource(t *testing.T) {
+	c := newClient(t)
+
+	res, err := c.Resource(context.Background(), "20170306040206", "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+	if b, err := io.ReadAll(res.Body); err != nil || len(b) == 0 {
+		t.Errorf("got %d bytes and error %v, want payload", len(b), err)
+	}
+
+	_, err = c.Resource(context.Background(), "20170306040206", "http://example.org/")
+	if !errors.Is(err, NotFoundError) {
+		t.Errorf("got error %v, want %v", err, NotFoundError)
+	}
+}
+
+func TestReports(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	report, err := c.CreateReport(ctx, SearchRequest{Url: "http://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetReport(ctx, report.GetId())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetId() != report.GetId() {
+		t.Errorf("got report %s, want %s", got.GetId(), report.GetId())
+	}
+	it, err := c.ListReports(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 1 {
+		t.Errorf("got %d reports and error %v, want 1 report", count, it.Err())
+	}
+	if err := c.DeleteReport(ctx, report.GetId()); err != nil {
+		t.Error(err)
+	}
+}
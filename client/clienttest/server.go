@@ -0,0 +1,124 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clienttest provides a fake gowarcserver for tests of clients.
+package clienttest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/badgeridx"
+	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/server/coreserver"
+	"github.com/nlnwa/gowarcserver/server/warcserver"
+)
+
+// Server is a server of the core API and the warcserver API of an index of
+// warc files in a temporary directory.
+type Server struct {
+	*httptest.Server
+}
+
+type Options struct {
+	Files  []string
+	Limits api.QueryLimits
+}
+
+type Option func(opts *Options)
+
+// WithFiles sets the paths of the warc files to index.
+func WithFiles(paths ...string) Option {
+	return func(opts *Options) {
+		opts.Files = append(opts.Files, paths...)
+	}
+}
+
+// WithLimits sets the query limits of the server.
+func WithLimits(limits api.QueryLimits) Option {
+	return func(opts *Options) {
+		opts.Limits = limits
+	}
+}
+
+// NewServer indexes the warc files and starts a server of them. The server is
+// closed when the test ends.
+func NewServer(tb testing.TB, options ...Option) *Server {
+	tb.Helper()
+
+	opts := new(Options)
+	for _, apply := range options {
+		apply(opts)
+	}
+
+	db, err := badgeridx.NewDB(badgeridx.WithDir(tb.TempDir()), badgeridx.WithoutBadgerLogging())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(db.Close)
+
+	indexer := index.NewIndexer(db)
+	for _, path := range opts.Files {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		indexer(path)
+	}
+	db.FlushBatch()
+
+	l := &loader.Loader{
+		StorageRefResolver: db,
+		RecordLoader:       loader.FileStorageLoader{FilePathResolver: db},
+		RevisitResolver:    warcserver.RevisitResolver{CdxAPI: db},
+	}
+	mw := func(h http.Handler) http.Handler {
+		return h
+	}
+	router := httprouter.New()
+	warcserver.Register(warcserver.Handler{
+		CdxAPI:     db,
+		FileAPI:    db,
+		IdAPI:      db,
+		WarcLoader: l,
+		Config: &warcserver.Config{
+			Limits: opts.Limits,
+		},
+	}, router, mw, "/warcserver")
+	coreserver.Register(coreserver.Handler{
+		CdxAPI:             db,
+		FileAPI:            db,
+		IdAPI:              db,
+		ReportAPI:          db,
+		StorageRefResolver: db,
+		DebugAPI:           db,
+		HistogramAPI:       db,
+		UrlAPI:             db,
+		WarcLoader:         l,
+		Limits:             opts.Limits,
+		WebPath:            "/warcserver/web",
+	}, router, mw, "")
+
+	server := httptest.NewServer(router)
+	tb.Cleanup(server.Close)
+
+	return &Server{Server: server}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type clientError string
+
+// BadRequestError is matched by errors of requests the server rejected as invalid.
+const BadRequestError clientError = "bad request"
+
+// NotFoundError is matched by errors of requests for something the server does not have.
+const NotFoundError clientError = "not found"
+
+// UnavailableError is matched by errors of queries that exceeded the limits of the server.
+const UnavailableError clientError = "unavailable"
+
+// NotImplementedError is matched by errors of requests the server does not support.
+const NotImplementedError clientError = "not implemented"
+
+func (e clientError) Error() string {
+	return string(e)
+}
+
+// ResponseError is an error reported by the server, either as the status of a
+// response or in-band in a stream of results.
+type ResponseError struct {
+	// StatusCode is the HTTP status code of the error.
+	StatusCode int
+	// Message is the error message of the server.
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether the status code of the error is the status code of target.
+func (e *ResponseError) Is(target error) bool {
+	var c clientError
+	if !errors.As(target, &c) {
+		return false
+	}
+	switch c {
+	case BadRequestError:
+		return e.StatusCode == http.StatusBadRequest
+	case NotFoundError:
+		return e.StatusCode == http.StatusNotFound
+	case UnavailableError:
+		return e.StatusCode == http.StatusServiceUnavailable
+	case NotImplementedError:
+		return e.StatusCode == http.StatusNotImplemented
+	}
+	return false
+}
+
+// maxErrorSize is the maximum size of an error message read from a response.
+const maxErrorSize = 64 * 1024
+
+// responseError reads the error of res and closes its body.
+func responseError(res *http.Response) error {
+	defer res.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorSize))
+	return &ResponseError{
+		StatusCode: res.StatusCode,
+		Message:    strings.TrimSpace(string(b)),
+	}
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxLineSize is the maximum size of a line of a stream.
+const maxLineSize = 1024 * 1024
+
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// errorLine is a line of a stream reporting an error.
+type errorLine struct {
+	Error *struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+var errorPrefix = []byte(`{"error":`)
+
+// stream reads the lines of a newline delimited json response.
+//
+// The stream ends at the first error, which is either an error line or the
+// status of the Stream-Status trailer.
+type stream struct {
+	res     *http.Response
+	scanner *bufio.Scanner
+	line    []byte
+	err     error
+	done    bool
+}
+
+func newStream(res *http.Response) *stream {
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &stream{res: res, scanner: scanner}
+}
+
+// next advances the stream to the next line, and returns false when the stream
+// has ended or failed.
+func (s *stream) next() bool {
+	if s.done {
+		return false
+	}
+	for s.scanner.Scan() {
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if bytes.HasPrefix(line, errorPrefix) {
+			var e errorLine
+			if err := json.Unmarshal(line, &e); err == nil && e.Error != nil {
+				// the rest of the stream is discarded to read the trailers
+				_, _ = io.Copy(io.Discard, s.res.Body)
+				s.fail(&ResponseError{StatusCode: e.Error.Status, Message: e.Error.Message})
+				return false
+			}
+		}
+		s.line = line
+		return true
+	}
+	if err := s.scanner.Err(); err != nil {
+		s.fail(err)
+		return false
+	}
+	// the trailers are known when the body is read to the end
+	if status := s.res.Trailer.Get(api.HeaderStreamStatus); status != "" && status != strconv.Itoa(http.StatusOK) {
+		code, _ := strconv.Atoi(status)
+		s.fail(&ResponseError{StatusCode: code})
+		return false
+	}
+	s.done = true
+	_ = s.Close()
+	return false
+}
+
+// fail ends the stream with err.
+func (s *stream) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.done = true
+	_ = s.Close()
+}
+
+// Err returns the error that ended the iteration, if any.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// Close ends the iteration. It must be called if the iteration is abandoned before it has ended.
+func (s *stream) Close() error {
+	s.done = true
+	return s.res.Body.Close()
+}
+
+// CdxIterator iterates over the results of a search.
+type CdxIterator struct {
+	*stream
+	cdx *schema.Cdx
+}
+
+// Next advances the iterator to the next result and returns false when there are no more results.
+func (it *CdxIterator) Next() bool {
+	if !it.next() {
+		return false
+	}
+	cdx := new(schema.Cdx)
+	if err := unmarshalOptions.Unmarshal(it.line, cdx); err != nil {
+		it.fail(fmt.Errorf("failed to unmarshal cdx: %w", err))
+		return false
+	}
+	it.cdx = cdx
+	return true
+}
+
+// Cdx returns the current result.
+func (it *CdxIterator) Cdx() *schema.Cdx {
+	return it.cdx
+}
+
+// ResumeKey returns the key to resume the search from, or empty if there are no
+// more results. It is known when the iteration has ended.
+func (it *CdxIterator) ResumeKey() string {
+	return it.res.Trailer.Get(api.HeaderResumeKey)
+}
+
+// Truncated returns the reason the results were truncated by the limits of the
+// server, or empty if they were not. It is known when the iteration has ended.
+func (it *CdxIterator) Truncated() string {
+	return it.res.Trailer.Get(api.HeaderTruncated)
+}
+
+// FileInfoIterator iterates over the file infos of the file index.
+type FileInfoIterator struct {
+	*stream
+	fileInfo *schema.FileInfo
+}
+
+// Next advances the iterator to the next file info and returns false when there are no more file infos.
+func (it *FileInfoIterator) Next() bool {
+	if !it.next() {
+		return false
+	}
+	fileInfo := new(schema.FileInfo)
+	if err := unmarshalOptions.Unmarshal(it.line, fileInfo); err != nil {
+		it.fail(fmt.Errorf("failed to unmarshal file info: %w", err))
+		return false
+	}
+	it.fileInfo = fileInfo
+	return true
+}
+
+// FileInfo returns the current file info.
+func (it *FileInfoIterator) FileInfo() *schema.FileInfo {
+	return it.fileInfo
+}
+
+// StorageRef is the location of a record.
+type StorageRef struct {
+	Id       string `json:"id"`
+	Filename string `json:"filename"`
+	Offset   int64  `json:"offset"`
+}
+
+// StorageRefIterator iterates over the storage refs of the id index.
+type StorageRefIterator struct {
+	*stream
+	ref StorageRef
+}
+
+// Next advances the iterator to the next storage ref and returns false when there are no more storage refs.
+func (it *StorageRefIterator) Next() bool {
+	if !it.next() {
+		return false
+	}
+	var ref StorageRef
+	if err := json.Unmarshal(it.line, &ref); err != nil {
+		it.fail(fmt.Errorf("failed to unmarshal storage ref: %w", err))
+		return false
+	}
+	it.ref = ref
+	return true
+}
+
+// StorageRef returns the current storage ref.
+func (it *StorageRefIterator) StorageRef() StorageRef {
+	return it.ref
+}
+
+// ReportIterator iterates over reports.
+type ReportIterator struct {
+	*stream
+	report *schema.Report
+}
+
+// Next advances the iterator to the next report and returns false when there are no more reports.
+func (it *ReportIterator) Next() bool {
+	if !it.next() {
+		return false
+	}
+	report := new(schema.Report)
+	if err := unmarshalOptions.Unmarshal(it.line, report); err != nil {
+		it.fail(fmt.Errorf("failed to unmarshal report: %w", err))
+		return false
+	}
+	it.report = report
+	return true
+}
+
+// Report returns the current report.
+func (it *ReportIterator) Report() *schema.Report {
+	return it.report
+}
+
+// RecordIterator iterates over the records of the results of a search.
+type RecordIterator struct {
+	ctx    context.Context
+	client *Client
+	cdx    *CdxIterator
+	record gowarc.WarcRecord
+	err    error
+}
+
+// Next closes the current record, advances the iterator to the record of the
+// next result and returns false when there are no more results.
+func (it *RecordIterator) Next() bool {
+	it.closeRecord()
+	if it.err != nil || !it.cdx.Next() {
+		return false
+	}
+	record, err := it.client.Record(it.ctx, it.cdx.Cdx().GetRid())
+	if err != nil {
+		it.err = fmt.Errorf("failed to load record %s: %w", it.cdx.Cdx().GetRid(), err)
+		_ = it.cdx.Close()
+		return false
+	}
+	it.record = record
+	return true
+}
+
+// Record returns the current record. It is closed by the next call to Next or Close.
+func (it *RecordIterator) Record() gowarc.WarcRecord {
+	return it.record
+}
+
+// Cdx returns the result of the current record.
+func (it *RecordIterator) Cdx() *schema.Cdx {
+	return it.cdx.Cdx()
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *RecordIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.cdx.Err()
+}
+
+// Close closes the current record and ends the iteration.
+func (it *RecordIterator) Close() error {
+	it.closeRecord()
+	return it.cdx.Close()
+}
+
+func (it *RecordIterator) closeRecord() {
+	if it.record != nil {
+		_ = it.record.Close()
+		it.record = nil
+	}
+}
@@ -47,15 +47,20 @@ func NewCommand() *cobra.Command {
 		RunE: indexCmd,
 	}
 	// index options
-	cmd.Flags().StringP("index-source", "s", "file", `index source: "file" or "kafka"`)
+	cmd.Flags().StringP("index-source", "s", "file", `index source: "file", "watch" or "kafka"`)
 	cmd.Flags().StringP("index-format", "o", "cdxj", `index format: "cdxj", "cdxpb", "toc", badger", "tikv"`)
 	cmd.Flags().StringSlice("index-include", nil, "only include files matching these regular expressions")
 	cmd.Flags().StringSlice("index-exclude", nil, "exclude files matching these regular expressions")
 	cmd.Flags().Int("index-workers", 8, "number of index workers")
+	cmd.Flags().StringSlice("index-record-types", []string{"response", "revisit"}, `record types to index: "response", "revisit", "resource", "request", "metadata" or "conversion"`)
+	cmd.Flags().Bool("index-non-http", false, "index response, revisit and request records that are not of content type application/http (e.g. dns)")
+	cmd.Flags().String("index-file-identity", index.FileIdentityName, `how files are identified in the index: "name" (filename) or "path" (path relative to the outermost of index-file-roots containing the file, prefixed by the name of that root if the roots are disjoint)`)
+	cmd.Flags().StringSlice("index-file-roots", nil, `root directories of the relative paths used by file identity "path"`)
 
 	// auto indexer options
 	cmd.Flags().StringSliceP("file-paths", "f", []string{"./testdata"}, "directories to search for warc files in")
 	cmd.Flags().Int("file-max-depth", 4, "maximum directory recursion")
+	cmd.Flags().Duration("file-debounce", index.DefaultDebounce, "how long a file must be left unchanged before it is indexed (watch)")
 
 	// kafka indexer options
 	cmd.Flags().StringSlice("kafka-brokers", nil, "the list of broker addresses used to connect to the kafka cluster")
@@ -89,6 +94,11 @@ func NewCommand() *cobra.Command {
 func indexCmd(_ *cobra.Command, _ []string) error {
 	var w index.RecordWriter
 
+	fileIdentity, err := index.ParseFileIdentity(viper.GetString("index-file-identity"), viper.GetStringSlice("index-file-roots"))
+	if err != nil {
+		return err
+	}
+
 	indexFormat := viper.GetString("index-format")
 	switch indexFormat {
 	case "cdxj":
@@ -101,6 +111,7 @@ func indexCmd(_ *cobra.Command, _ []string) error {
 			tikvidx.WithBatchMaxSize(viper.GetInt("tikv-batch-max-size")),
 			tikvidx.WithBatchMaxWait(viper.GetDuration("tikv-batch-max-wait")),
 			tikvidx.WithDatabase(viper.GetString("tikv-database")),
+			tikvidx.WithFileIdentity(fileIdentity),
 		)
 		if err != nil {
 			return err
@@ -114,6 +125,7 @@ func indexCmd(_ *cobra.Command, _ []string) error {
 			badgeridx.WithBatchMaxSize(viper.GetInt("badger-batch-max-size")),
 			badgeridx.WithBatchMaxWait(viper.GetDuration("badger-batch-max-wait")),
 			badgeridx.WithDatabase(viper.GetString("badger-database")),
+			badgeridx.WithFileIdentity(fileIdentity),
 		)
 		if err != nil {
 			return err
@@ -146,9 +158,17 @@ func indexCmd(_ *cobra.Command, _ []string) error {
 		}
 	}
 
+	recordTypes, err := index.ParseRecordTypes(viper.GetStringSlice("index-record-types"))
+	if err != nil {
+		return err
+	}
+
 	indexer := index.NewIndexer(w,
 		index.WithIncludes(includes...),
 		index.WithExcludes(excludes...),
+		index.WithRecordTypes(recordTypes),
+		index.WithNonHttp(viper.GetBool("index-non-http")),
+		index.WithFileIdentity(fileIdentity),
 	)
 	queue := index.NewWorkQueue(indexer,
 		viper.GetInt("index-workers"),
@@ -168,6 +188,13 @@ func indexCmd(_ *cobra.Command, _ []string) error {
 			index.WithPaths(viper.GetStringSlice("file-paths")),
 			index.WithExcludeDirs(excludes...),
 		)
+	case "watch":
+		runner = index.NewWatchIndexer(queue,
+			index.WithMaxDepth(viper.GetInt("file-max-depth")),
+			index.WithPaths(viper.GetStringSlice("file-paths")),
+			index.WithExcludeDirs(excludes...),
+			index.WithDebounce(viper.GetDuration("file-debounce")),
+		)
 	case "kafka":
 		runner = index.NewKafkaIndexer(queue,
 			index.WithBrokers(viper.GetStringSlice("kafka-brokers")),
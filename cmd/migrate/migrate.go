@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"runtime"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/badgeridx"
+	"github.com/nlnwa/gowarcserver/internal/tikvidx"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the database to another file identity",
+		Long: `Migrate the database to another file identity.
+
+Changes the identity of the files in the file index and rewrites the storage refs
+of the indexed records without reading the warc files again.
+
+Files that were indexed with the same filename in different directories can not
+be told apart by their storage refs. Reindex those files after migrating.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+			return nil
+		},
+		RunE: migrateCmd,
+	}
+	// index options
+	cmd.Flags().StringP("index-format", "o", "badger", `index format: "badger" or "tikv"`)
+	cmd.Flags().String("index-file-identity", index.FileIdentityName, `how files are identified in the index: "name" (filename) or "path" (path relative to the outermost of index-file-roots containing the file, prefixed by the name of that root if the roots are disjoint)`)
+	cmd.Flags().StringSlice("index-file-roots", nil, `root directories of the relative paths used by file identity "path"`)
+
+	// badger options
+	cmd.Flags().String("badger-dir", "./warcdb", "path to index database")
+	cmd.Flags().String("badger-database", "", "name of badger database")
+
+	// tikv options
+	cmd.Flags().StringSlice("tikv-pd-addr", nil, "host:port of TiKV placement driver")
+	cmd.Flags().String("tikv-database", "", "name of tikv database")
+
+	return cmd
+}
+
+func migrateCmd(_ *cobra.Command, _ []string) error {
+	fileIdentity, err := index.ParseFileIdentity(viper.GetString("index-file-identity"), viper.GetStringSlice("index-file-roots"))
+	if err != nil {
+		return err
+	}
+
+	var migrator index.FileIdentityMigrator
+
+	indexFormat := viper.GetString("index-format")
+	switch indexFormat {
+	case "badger":
+		// Increase GOMAXPROCS as recommended by badger
+		// https://github.com/dgraph-io/badger#are-there-any-go-specific-settings-that-i-should-use
+		runtime.GOMAXPROCS(128)
+		db, err := badgeridx.NewDB(
+			badgeridx.WithDir(viper.GetString("badger-dir")),
+			badgeridx.WithDatabase(viper.GetString("badger-database")),
+		)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		migrator = db
+	case "tikv":
+		db, err := tikvidx.NewDB(
+			tikvidx.WithPDAddress(viper.GetStringSlice("tikv-pd-addr")),
+			tikvidx.WithDatabase(viper.GetString("tikv-database")),
+		)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		migrator = db
+	default:
+		return fmt.Errorf("unknown index format: %s", indexFormat)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return migrator.MigrateFileIdentity(ctx, fileIdentity)
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"runtime"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/badgeridx"
+	"github.com/nlnwa/gowarcserver/internal/tikvidx"
+)
+
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile the file index with the filesystem",
+		Long: `Reconcile the file index with the filesystem.
+
+Files that no longer exist at their indexed path are searched for in the
+reconcile roots. If a file with the same filename, size and modification time
+is found the indexed path is updated, and if the identity of the file has changed
+the storage refs of its records are rewritten. Otherwise the indexed records of
+the file are removed.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("failed to bind flags: %w", err)
+			}
+			return nil
+		},
+		RunE: reconcileCmd,
+	}
+	// index options
+	cmd.Flags().StringP("index-format", "o", "badger", `index format: "badger" or "tikv"`)
+	cmd.Flags().String("index-file-identity", index.FileIdentityName, `how files are identified in the index: "name" (filename) or "path" (path relative to the outermost of index-file-roots containing the file, prefixed by the name of that root if the roots are disjoint)`)
+	cmd.Flags().StringSlice("index-file-roots", nil, `root directories of the relative paths used by file identity "path"`)
+
+	// reconcile options
+	cmd.Flags().StringSlice("reconcile-roots", nil, "directories to search for files that have moved")
+	cmd.Flags().Int("file-max-depth", 4, "maximum directory recursion depth")
+	cmd.Flags().Bool("dry-run", false, "only log the changes that would be made")
+
+	// badger options
+	cmd.Flags().String("badger-dir", "./warcdb", "path to index database")
+	cmd.Flags().String("badger-database", "", "name of badger database")
+
+	// tikv options
+	cmd.Flags().StringSlice("tikv-pd-addr", nil, "host:port of TiKV placement driver")
+	cmd.Flags().String("tikv-database", "", "name of tikv database")
+
+	return cmd
+}
+
+func reconcileCmd(_ *cobra.Command, _ []string) error {
+	fileIdentity, err := index.ParseFileIdentity(viper.GetString("index-file-identity"), viper.GetStringSlice("index-file-roots"))
+	if err != nil {
+		return err
+	}
+	roots := viper.GetStringSlice("reconcile-roots")
+	if len(roots) == 0 {
+		return fmt.Errorf("at least one reconcile root is required")
+	}
+
+	var reconciler index.Reconciler
+
+	indexFormat := viper.GetString("index-format")
+	switch indexFormat {
+	case "badger":
+		// Increase GOMAXPROCS as recommended by badger
+		// https://github.com/dgraph-io/badger#are-there-any-go-specific-settings-that-i-should-use
+		runtime.GOMAXPROCS(128)
+		db, err := badgeridx.NewDB(
+			badgeridx.WithDir(viper.GetString("badger-dir")),
+			badgeridx.WithDatabase(viper.GetString("badger-database")),
+		)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		reconciler = db
+	case "tikv":
+		db, err := tikvidx.NewDB(
+			tikvidx.WithPDAddress(viper.GetStringSlice("tikv-pd-addr")),
+			tikvidx.WithDatabase(viper.GetString("tikv-database")),
+		)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		reconciler = db
+	default:
+		return fmt.Errorf("unknown index format: %s", indexFormat)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	locate := index.NewFileLocator(fileIdentity, roots, viper.GetInt("file-max-depth"))
+
+	return reconciler.Reconcile(ctx, locate, viper.GetBool("dry-run"))
+}
@@ -23,6 +23,8 @@ import (
 	"strings"
 
 	"github.com/nlnwa/gowarcserver/cmd/index"
+	"github.com/nlnwa/gowarcserver/cmd/migrate"
+	"github.com/nlnwa/gowarcserver/cmd/reconcile"
 	"github.com/nlnwa/gowarcserver/cmd/reset"
 	"github.com/nlnwa/gowarcserver/cmd/serve"
 	"github.com/nlnwa/gowarcserver/cmd/version"
@@ -57,6 +59,8 @@ func NewCommand() *cobra.Command {
 	cmd.AddCommand(index.NewCommand())
 	cmd.AddCommand(version.NewCommand())
 	cmd.AddCommand(reset.NewCommand())
+	cmd.AddCommand(migrate.NewCommand())
+	cmd.AddCommand(reconcile.NewCommand())
 	return cmd
 }
 
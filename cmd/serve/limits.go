@@ -0,0 +1,92 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package serve
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/spf13/viper"
+)
+
+// queryEndpoints are the endpoints that can be configured with query limits.
+var queryEndpoints = []string{
+	api.DefaultEndpoint,
+	"cdx",
+	"batch",
+	"debug",
+	"histogram",
+	"hosts",
+	"urls",
+	"warcserver-cdx",
+	"warcserver-web",
+	"wayback-cdx",
+	"wayback-available",
+	"memento-timemap",
+	"memento-timegate",
+	"grpc",
+}
+
+// defaultQueryLimits are the query limits of endpoints that are not configured.
+var defaultQueryLimits = api.QueryLimits{
+	"histogram":         {Timeout: 30 * time.Second},
+	"warcserver-cdx":    {Timeout: 30 * time.Second},
+	"warcserver-web":    {Timeout: 10 * time.Second},
+	"wayback-cdx":       {Timeout: 30 * time.Second},
+	"wayback-available": {Timeout: 10 * time.Second},
+	"memento-timemap":   {Timeout: 30 * time.Second},
+	"memento-timegate":  {Timeout: 10 * time.Second},
+}
+
+// parseQueryLimits returns the default query limits overridden by the query-limits configuration, e.g.
+//
+//	query-limits:
+//	  cdx:
+//	    timeout: 30s
+//	    max-keys: 100000
+func parseQueryLimits() (api.QueryLimits, error) {
+	limits := api.QueryLimits{}
+	for endpoint, l := range defaultQueryLimits {
+		limits[endpoint] = l
+	}
+	for endpoint := range viper.GetStringMap("query-limits") {
+		if !slices.Contains(queryEndpoints, endpoint) {
+			return nil, fmt.Errorf("unknown endpoint of query limits: %s, must be one of %v", endpoint, queryEndpoints)
+		}
+		key := "query-limits." + endpoint
+		l := limits[endpoint]
+		if viper.IsSet(key + ".timeout") {
+			l.Timeout = viper.GetDuration(key + ".timeout")
+		}
+		if viper.IsSet(key + ".max-keys") {
+			l.MaxKeys = viper.GetInt(key + ".max-keys")
+		}
+		if viper.IsSet(key + ".max-values") {
+			l.MaxValues = viper.GetInt(key + ".max-values")
+		}
+		if viper.IsSet(key + ".max-results") {
+			l.MaxResults = viper.GetInt(key + ".max-results")
+		}
+		if l.Timeout < 0 || l.MaxKeys < 0 || l.MaxValues < 0 || l.MaxResults < 0 {
+			return nil, fmt.Errorf("query limits of endpoint %s must not be negative", endpoint)
+		}
+		limits[endpoint] = l
+	}
+	return limits, nil
+}
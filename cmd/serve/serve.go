@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -32,6 +33,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 
 	"github.com/nlnwa/gowarcserver/index"
 	"github.com/nlnwa/gowarcserver/internal/badgeridx"
@@ -39,7 +41,10 @@ import (
 	"github.com/nlnwa/gowarcserver/internal/tikvidx"
 	"github.com/nlnwa/gowarcserver/loader"
 	"github.com/nlnwa/gowarcserver/server/coreserver"
+	"github.com/nlnwa/gowarcserver/server/grpcserver"
+	"github.com/nlnwa/gowarcserver/server/mementoserver"
 	"github.com/nlnwa/gowarcserver/server/warcserver"
+	"github.com/nlnwa/gowarcserver/server/waybackserver"
 )
 
 func NewCommand() *cobra.Command {
@@ -59,20 +64,43 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().IntP("port", "p", 9999, "server port")
 	cmd.Flags().String("path-prefix", "", "path prefix for all server endpoints")
 	cmd.Flags().Bool("log-requests", false, "log incoming http requests")
+	cmd.Flags().Int("grpc-port", 0, "gRPC server port, 0 disables the gRPC API")
 
 	// warcserver API options
 	cmd.Flags().Int("warcserver-prefix-max-records", 1000, "limit number of responses for prefix searches (warcserver)")
+	cmd.Flags().String("warcserver-collection", "", "collection name of the Warcserver-Source-Coll header of records (warcserver)")
+	cmd.Flags().String("warcserver-payload-decoding", "none", `how replayed payloads are decoded: "none", "transfer" (de-chunk) or "content" (de-chunk and decompress)`)
+	cmd.Flags().Bool("warcserver-skip-self-redirects", false, "skip captures of redirects to the same resource at the same timestamp in favour of the next-closest capture (warcserver)")
+	cmd.Flags().Int("warcserver-redirect-max-hops", 0, "maximum number of redirects followed before redirecting the client, 0 redirects the client (warcserver)")
+	cmd.Flags().Duration("warcserver-redirect-max-drift", 0, "maximum time between the capture of a redirect and the capture of its target, 0 is unlimited (warcserver)")
+	cmd.Flags().Bool("warcserver-fuzzy-match", false, "fuzzy match urls without an exact capture (warcserver)")
+	cmd.Flags().String("warcserver-fuzzy-rules", "", "path to a YAML file of fuzzy match rules in pywb's rules format, applied before the default rule (warcserver)")
+
+	// core API options
+	cmd.Flags().Int("batch-concurrency", coreserver.DefaultBatchConcurrency, "number of concurrent lookups of a batch")
+
+	// wayback API options
+	cmd.Flags().String("wayback-replay-url", "", `replay url template of the availability API where "{timestamp}" and "{url}" are replaced, defaults to the warcserver web endpoint`)
 
 	// index options
-	cmd.Flags().StringP("index-source", "s", "file", `index source: "file" or "kafka"`)
+	cmd.Flags().StringP("index-source", "s", "file", `index source: "file", "watch" or "kafka"`)
 	cmd.Flags().StringP("index-format", "o", "badger", `index format: "badger", "tikv"`)
 	cmd.Flags().StringSlice("index-include", nil, "only include files matching these regular expressions")
 	cmd.Flags().StringSlice("index-exclude", nil, "exclude files matching these regular expressions")
 	cmd.Flags().Int("index-workers", 8, "number of index workers")
+	cmd.Flags().StringSlice("index-record-types", []string{"response", "revisit"}, `record types to index: "response", "revisit", "resource", "request", "metadata" or "conversion"`)
+	cmd.Flags().Bool("index-non-http", false, "index response, revisit and request records that are not of content type application/http (e.g. dns)")
+	cmd.Flags().String("index-file-identity", index.FileIdentityName, `how files are identified in the index: "name" (filename) or "path" (path relative to the outermost of index-file-roots containing the file, prefixed by the name of that root if the roots are disjoint)`)
+	cmd.Flags().StringSlice("index-file-roots", nil, `root directories of the relative paths used by file identity "path"`)
 
 	// auto indexer options
 	cmd.Flags().StringSlice("file-paths", []string{"./testdata"}, "list of paths to warc files or directories containing warc files")
 	cmd.Flags().Int("file-max-depth", 4, "maximum directory recursion depth")
+	cmd.Flags().Duration("file-debounce", index.DefaultDebounce, "how long a file must be left unchanged before it is indexed (watch)")
+
+	// reconcile options
+	cmd.Flags().Duration("reconcile-interval", 0, "how often to reconcile the file index with the filesystem, 0 disables reconciliation")
+	cmd.Flags().StringSlice("reconcile-roots", nil, "directories to search for files that have moved, defaults to file-paths")
 
 	// kafka indexer options
 	cmd.Flags().StringSlice("kafka-brokers", nil, "the list of broker addresses used to connect to the kafka cluster")
@@ -118,12 +146,51 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		}
 	}
 
+	// parse record types
+	recordTypes, err := index.ParseRecordTypes(viper.GetStringSlice("index-record-types"))
+	if err != nil {
+		return err
+	}
+
+	// parse query limits
+	queryLimits, err := parseQueryLimits()
+	if err != nil {
+		return err
+	}
+
+	// parse payload decoding
+	payloadDecoding, err := loader.ParsePayloadDecoding(viper.GetString("warcserver-payload-decoding"))
+	if err != nil {
+		return err
+	}
+
+	// load fuzzy match rules
+	var fuzzyRules warcserver.FuzzyRules
+	if viper.GetBool("warcserver-fuzzy-match") {
+		if path := viper.GetString("warcserver-fuzzy-rules"); path != "" {
+			fuzzyRules, err = warcserver.LoadFuzzyRules(path)
+			if err != nil {
+				return err
+			}
+		}
+		fuzzyRules = append(fuzzyRules, warcserver.DefaultFuzzyRule)
+	}
+
+	// parse file identity
+	fileIdentity, err := index.ParseFileIdentity(viper.GetString("index-file-identity"), viper.GetStringSlice("index-file-roots"))
+	if err != nil {
+		return err
+	}
+
 	var writer index.RecordWriter
 	var fileApi index.FileAPI
 	var cdxApi index.CdxAPI
 	var idApi index.IdAPI
 	var reportApi index.ReportAPI
+	var reconciler index.Reconciler
 	var debugApi keyvalue.DebugAPI
+	var histogramApi index.HistogramAPI
+	var urlApi keyvalue.UrlAPI
 	var storageRefResolver loader.StorageRefResolver
 	var filePathResolver loader.FilePathResolver
 
@@ -137,6 +204,7 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 			badgeridx.WithBatchMaxWait(viper.GetDuration("badger-batch-max-wait")),
 			badgeridx.WithReadOnly(viper.GetString("index-source") == ""),
 			badgeridx.WithDatabase(viper.GetString("badger-database")),
+			badgeridx.WithFileIdentity(fileIdentity),
 		)
 		if err != nil {
 			return err
@@ -150,6 +218,9 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		idApi = db
 		reportApi = db
 		debugApi = db
+		histogramApi = db
+		urlApi = db
+		reconciler = db
 	case "tikv":
 		db, err := tikvidx.NewDB(
 			tikvidx.WithPDAddress(viper.GetStringSlice("tikv-pd-addr")),
@@ -157,6 +228,7 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 			tikvidx.WithBatchMaxWait(viper.GetDuration("tikv-batch-max-wait")),
 			tikvidx.WithDatabase(viper.GetString("tikv-database")),
 			tikvidx.WithReadOnly(viper.GetString("index-source") == ""),
+			tikvidx.WithFileIdentity(fileIdentity),
 		)
 		if err != nil {
 			return err
@@ -170,6 +242,9 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		idApi = db
 		reportApi = db
 		debugApi = db
+		histogramApi = db
+		urlApi = db
+		reconciler = db
 	default:
 		return fmt.Errorf("unknown index format: %s", indexFormat)
 	}
@@ -182,6 +257,9 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		indexer := index.NewIndexer(writer,
 			index.WithIncludes(includes...),
 			index.WithExcludes(excludes...),
+			index.WithRecordTypes(recordTypes),
+			index.WithNonHttp(viper.GetBool("index-non-http")),
+			index.WithFileIdentity(fileIdentity),
 		)
 		queue := index.NewWorkQueue(indexer,
 			viper.GetInt("index-workers"),
@@ -195,6 +273,13 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 				index.WithMaxDepth(viper.GetInt("file-max-depth")),
 				index.WithPaths(viper.GetStringSlice("file-paths")),
 			)
+		case "watch":
+			runner = index.NewWatchIndexer(queue,
+				index.WithMaxDepth(viper.GetInt("file-max-depth")),
+				index.WithPaths(viper.GetStringSlice("file-paths")),
+				index.WithExcludeDirs(excludes...),
+				index.WithDebounce(viper.GetDuration("file-debounce")),
+			)
 		case "kafka":
 			runner = index.NewKafkaIndexer(queue,
 				index.WithBrokers(viper.GetStringSlice("kafka-brokers")),
@@ -215,12 +300,35 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 			}
 		}()
 
+		if interval := viper.GetDuration("reconcile-interval"); interval > 0 {
+			roots := viper.GetStringSlice("reconcile-roots")
+			if len(roots) == 0 {
+				roots = viper.GetStringSlice("file-paths")
+			}
+			go func() {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						log.Info().Msg("Reconciling file index")
+						locate := index.NewFileLocator(fileIdentity, roots, viper.GetInt("file-max-depth"))
+						if err := reconciler.Reconcile(ctx, locate, false); err != nil {
+							log.Error().Err(err).Msg("Failed to reconcile file index")
+						}
+					}
+				}
+			}()
+		}
 	}
 
 	// create record loader
 	l := &loader.Loader{
 		StorageRefResolver: storageRefResolver,
 		RecordLoader:       loader.FileStorageLoader{FilePathResolver: filePathResolver},
+		RevisitResolver:    warcserver.RevisitResolver{CdxAPI: cdxApi},
 	}
 	// middleware chain
 	mw := func(h http.Handler) http.Handler {
@@ -245,9 +353,36 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		WarcLoader: l,
 		Config: &warcserver.Config{
 			PrefixSearchLimit: viper.GetInt("warcserver-prefix-max-records"),
+			Collection:        viper.GetString("warcserver-collection"),
+			PayloadDecoding:   payloadDecoding,
+			SkipSelfRedirects: viper.GetBool("warcserver-skip-self-redirects"),
+			MaxRedirectHops:   viper.GetInt("warcserver-redirect-max-hops"),
+			MaxRedirectDrift:  viper.GetDuration("warcserver-redirect-max-drift"),
+			FuzzyRules:        fuzzyRules,
+			MementoPath:       pathPrefix,
+			Limits:            queryLimits,
 		},
 	}, handler, mw, pathPrefix+"/warcserver")
 
+	// register Memento API
+	mementoserver.Register(mementoserver.Handler{
+		CdxAPI: cdxApi,
+		Config: &mementoserver.Config{
+			WebPath: pathPrefix + "/warcserver/web",
+			Limits:  queryLimits,
+		},
+	}, handler, mw, pathPrefix)
+
+	// register wayback API
+	waybackserver.Register(waybackserver.Handler{
+		CdxAPI: cdxApi,
+		Config: &waybackserver.Config{
+			ReplayUrl: viper.GetString("wayback-replay-url"),
+			WebPath:   pathPrefix + "/warcserver/web",
+			Limits:    queryLimits,
+		},
+	}, handler, mw, pathPrefix)
+
 	// register core API
 	coreserver.Register(coreserver.Handler{
 		CdxAPI:             cdxApi,
@@ -256,9 +391,54 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 		ReportAPI:          reportApi,
 		StorageRefResolver: storageRefResolver,
 		DebugAPI:           debugApi,
+		HistogramAPI:       histogramApi,
+		UrlAPI:             urlApi,
 		WarcLoader:         l,
+		BatchConcurrency:   viper.GetInt("batch-concurrency"),
+		Limits:             queryLimits,
+		WebPath:            pathPrefix + "/warcserver/web",
 	}, handler, mw, pathPrefix)
 
+	// optionally serve the gRPC API alongside the HTTP API
+	if grpcPort := viper.GetInt("grpc-port"); grpcPort > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+		if err != nil {
+			return err
+		}
+		grpcServer := grpc.NewServer()
+		grpcserver.Register(grpcserver.Server{
+			CdxAPI:     cdxApi,
+			FileAPI:    fileApi,
+			IdAPI:      idApi,
+			ReportAPI:  reportApi,
+			WarcLoader: l,
+			Limits:     queryLimits,
+		}, grpcServer)
+
+		go func() {
+			<-ctx.Done()
+
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(5 * time.Second):
+				grpcServer.Stop()
+			}
+		}()
+
+		go func() {
+			log.Info().Msgf("Starting gRPC server at :%v", grpcPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Error().Err(err).Msg("gRPC server has stopped")
+				cancel()
+			}
+		}()
+	}
+
 	port := viper.GetInt("port")
 	httpServer := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -279,7 +459,7 @@ func serveCmd(_ *cobra.Command, _ []string) error {
 
 	log.Info().Msgf("Starting server at :%v", port)
 
-	err := httpServer.ListenAndServe()
+	err = httpServer.ListenAndServe()
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -18,6 +18,7 @@ package index
 
 import (
 	"context"
+	"errors"
 
 	"github.com/nlnwa/gowarcserver/schema"
 	"github.com/nlnwa/whatwg-url/url"
@@ -27,6 +28,20 @@ type Deleter interface {
 	Delete(context.Context) error
 }
 
+// FileIdentityMigrator is implemented by databases that can change how indexed files are identified.
+type FileIdentityMigrator interface {
+	// MigrateFileIdentity changes the identity of indexed files and the storage refs referring to them.
+	MigrateFileIdentity(context.Context, FileIdentity) error
+}
+
+// Reconciler is implemented by databases that can reconcile the file index with the filesystem.
+type Reconciler interface {
+	// Reconcile updates the path and identity of files that have moved and
+	// removes the indexed records of files that have vanished. If dryRun is true the
+	// changes are only logged.
+	Reconcile(ctx context.Context, locate FileLocator, dryRun bool) error
+}
+
 type Runner interface {
 	Run(context.Context) error
 }
@@ -35,10 +50,24 @@ type indexError string
 
 const AlreadyIndexedError indexError = "already indexed"
 
+// InvalidResumeKeyError is returned when a search is resumed from a key outside of the search.
+const InvalidResumeKeyError indexError = "invalid resume key"
+
+// MaxKeysExceededError is returned when a search has scanned more keys than its budget allows.
+const MaxKeysExceededError indexError = "max keys exceeded"
+
+// MaxValuesExceededError is returned when a search has decoded more values than its budget allows.
+const MaxValuesExceededError indexError = "max values exceeded"
+
 func (a indexError) Error() string {
 	return string(a)
 }
 
+// IsBudgetExceeded returns true if err was returned because the budget of a search was exceeded.
+func IsBudgetExceeded(err error) bool {
+	return errors.Is(err, MaxKeysExceededError) || errors.Is(err, MaxValuesExceededError)
+}
+
 type DateRange interface {
 	Contains(int64) bool
 }
@@ -47,6 +76,28 @@ type Filter interface {
 	Eval(*schema.Cdx) bool
 }
 
+// IsEmptyFilter returns true if filter is known to pass every cdx record, so that
+// searches can scan keys only instead of decoding values to evaluate it.
+func IsEmptyFilter(filter Filter) bool {
+	f, ok := filter.(interface{ Empty() bool })
+	return ok && f.Empty()
+}
+
+// Collapser collapses adjacent results of a search.
+type Collapser interface {
+	// Collapse returns true if the result should be skipped because it is
+	// equal to the previous result. A result that is not skipped becomes the previous result.
+	Collapse(*schema.Cdx) bool
+}
+
+// Budget limits the work of a search.
+type Budget interface {
+	// ScanKey counts a scanned key and returns MaxKeysExceededError if the budget is exceeded.
+	ScanKey() error
+	// DecodeValue counts a decoded value and returns MaxValuesExceededError if the budget is exceeded.
+	DecodeValue() error
+}
+
 type Sort int
 
 const (
@@ -71,10 +122,26 @@ type Request interface {
 	Ssurt() string
 	Sort() Sort
 	DateRange() DateRange
+	// Filter returns the filter of the search. It is never nil, use IsEmptyFilter to
+	// check whether it passes every result.
 	Filter() Filter
 	Limit() int
 	Closest() string
 	MatchType() MatchType
+	// ResumeKey is the key of the last result of a previous search, or empty if
+	// the search is not resumed. The search continues after the resume key.
+	ResumeKey() string
+	// Collapse returns a new collapser for the search, or nil if results should not be collapsed.
+	Collapse() Collapser
+	// Budget returns a new budget for the search, or nil if the search is unlimited.
+	Budget() Budget
+}
+
+// HistogramAPI counts captures by time.
+type HistogramAPI interface {
+	// Histogram returns the number of captures matching req by the first n
+	// digits of their timestamps, e.g. by year if n is 4 and by day if n is 8.
+	Histogram(ctx context.Context, req Request, n int) (map[string]uint64, error)
 }
 
 type FileAPI interface {
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -29,6 +30,7 @@ import (
 type AutoIndexOptions struct {
 	MaxDepth int
 	Paths    []string
+	Debounce time.Duration
 	Options
 }
 type AutoIndexOption func(*AutoIndexOptions)
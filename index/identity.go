@@ -0,0 +1,125 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FileIdentityName identifies files by filename.
+	FileIdentityName = "name"
+	// FileIdentityPath identifies files by path relative to a root directory.
+	FileIdentityPath = "path"
+)
+
+// FileIdentity returns the identity of the file at path.
+//
+// The identity is used to refer to the file in storage refs and is the key of the file index.
+type FileIdentity func(path string) (string, error)
+
+// BaseName identifies a file by its filename.
+func BaseName(path string) (string, error) {
+	return filepath.Base(path), nil
+}
+
+// RelativePath returns a FileIdentity that identifies a file by its path
+// relative to the outermost of roots containing the file, so that files below
+// nested roots keep distinct identities.
+//
+// If the roots are not all below one outermost root, the path is prefixed by
+// the name of the outermost root, e.g. "a/file.warc.gz" and "b/file.warc.gz"
+// for roots "/data/a" and "/data/b", so that files below disjoint roots keep
+// distinct identities. It is an error if disjoint roots have the same name.
+func RelativePath(roots ...string) (FileIdentity, error) {
+	var absRoots []string
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		absRoots = append(absRoots, abs)
+	}
+	// the outermost roots are the roots that are not below another root
+	var outermost []string
+	for i, root := range absRoots {
+		isOutermost := true
+		for j, other := range absRoots {
+			if rel, ok := relativeTo(other, root); ok && (rel != "." || j < i) {
+				isOutermost = false
+				break
+			}
+		}
+		if isOutermost {
+			outermost = append(outermost, root)
+		}
+	}
+	labelled := len(outermost) > 1
+	if labelled {
+		names := make(map[string]string)
+		for _, root := range outermost {
+			name := filepath.Base(root)
+			if other, ok := names[name]; ok {
+				return nil, fmt.Errorf("roots %s and %s have the same name and would give files the same identity", other, root)
+			}
+			names[name] = root
+		}
+	}
+	return func(path string) (string, error) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		for _, root := range outermost {
+			rel, ok := relativeTo(root, abs)
+			if !ok {
+				continue
+			}
+			if labelled {
+				rel = filepath.Join(filepath.Base(root), rel)
+			}
+			return filepath.ToSlash(rel), nil
+		}
+		return "", fmt.Errorf("file is not below any of the roots %v: %s", roots, path)
+	}, nil
+}
+
+// relativeTo returns the path of abs relative to root and true if abs is root or below root.
+func relativeTo(root string, abs string) (string, bool) {
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// ParseFileIdentity returns the FileIdentity of the named identity scheme.
+func ParseFileIdentity(scheme string, roots []string) (FileIdentity, error) {
+	switch scheme {
+	case "", FileIdentityName:
+		return BaseName, nil
+	case FileIdentityPath:
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("file identity %q requires at least one root", FileIdentityPath)
+		}
+		return RelativePath(roots...)
+	default:
+		return nil, fmt.Errorf("unknown file identity: %s", scheme)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import "testing"
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		roots   []string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "below root", roots: []string{"/data"}, path: "/data/a/file.warc.gz", want: "a/file.warc.gz"},
+		{name: "directly below root", roots: []string{"/data/"}, path: "/data/file.warc.gz", want: "file.warc.gz"},
+		{name: "nested roots", roots: []string{"/data/collection", "/data"}, path: "/data/collection/a/file.warc.gz", want: "collection/a/file.warc.gz"},
+		{name: "unclean path", roots: []string{"/data"}, path: "/other/../data/b/file.warc.gz", want: "b/file.warc.gz"},
+		{name: "disjoint roots", roots: []string{"/data/a", "/data/b"}, path: "/data/a/crawl-00001.warc.gz", want: "a/crawl-00001.warc.gz"},
+		{name: "other disjoint root", roots: []string{"/data/a", "/data/b"}, path: "/data/b/crawl-00001.warc.gz", want: "b/crawl-00001.warc.gz"},
+		{name: "disjoint and nested roots", roots: []string{"/data", "/data/collection", "/other"}, path: "/data/collection/file.warc.gz", want: "data/collection/file.warc.gz"},
+		{name: "outside of root", roots: []string{"/data"}, path: "/database/file.warc.gz", wantErr: true},
+		{name: "above root", roots: []string{"/data"}, path: "/file.warc.gz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := RelativePath(tt.roots...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := identity(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := RelativePath("/data/a", "/backup/a"); err == nil {
+		t.Error("expected error when disjoint roots have the same name")
+	}
+}
+
+func TestParseFileIdentity(t *testing.T) {
+	if _, err := ParseFileIdentity(FileIdentityPath, nil); err == nil {
+		t.Error("expected error when no roots are given")
+	}
+	if _, err := ParseFileIdentity("hash", nil); err == nil {
+		t.Error("expected error for unknown file identity")
+	}
+	identity, err := ParseFileIdentity("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := identity("/data/a/file.warc.gz"); got != "file.warc.gz" {
+		t.Errorf("got %s, want %s", got, "file.warc.gz")
+	}
+}
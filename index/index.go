@@ -19,7 +19,6 @@ package index
 import (
 	"errors"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/nlnwa/gowarc"
@@ -30,13 +29,25 @@ type Indexer interface {
 	Index(string) error
 }
 
+// Committer is implemented by indexers that only mark a file as indexed
+// when all records of the file have been durably written.
+type Committer interface {
+	// Commit is called when all records of the file have been written to the indexer.
+	Commit(string) error
+	// Abort is called when indexing of the file failed, so that it is indexed again on the next run.
+	Abort(string)
+}
+
 func NewIndexer(w RecordWriter, options ...Option) func(string) {
-	opts := new(Options)
+	opts := &Options{
+		identity: BaseName,
+	}
 	for _, apply := range options {
 		apply(opts)
 	}
 
 	indexer, ok := w.(Indexer)
+	committer, isCommitter := w.(Committer)
 
 	return func(path string) {
 		// file
@@ -58,31 +69,42 @@ func NewIndexer(w RecordWriter, options ...Option) func(string) {
 			}
 		}
 
-		index(path, w, opts)
+		err := index(path, w, opts)
+		if !isCommitter {
+			return
+		}
+		if err != nil {
+			committer.Abort(path)
+			return
+		}
+		if err := committer.Commit(path); err != nil {
+			log.Error().Err(err).Msgf("Failed to commit: %s", path)
+		}
 	}
 }
 
-func index(filename string, r RecordWriter, opts *Options) {
+func index(filename string, r RecordWriter, opts *Options) error {
 	start := time.Now()
 
 	filter := func(wr gowarc.WarcRecord, validation *gowarc.Validation) bool {
 		if !validation.Valid() {
 			log.Debug().Msg(validation.Error())
 		}
-		// only index response and revisit records of type application/http
-		if wr.Type() == gowarc.Response || wr.Type() == gowarc.Revisit {
-			// of type application/http
-			if strings.HasPrefix(wr.WarcHeader().Get(gowarc.ContentType), gowarc.ApplicationHttp) {
-				return true
-			}
-		}
-		return false
+		return opts.accept(wr)
 	}
 
-	count, total, err := readFile(filename, r, filter, opts.warcRecordOption...)
+	name, err := opts.identity(filename)
+	if err != nil {
+		log.Error().Err(err).Msgf("Indexing failed: %s", filename)
+		return err
+	}
+
+	count, total, err := readFile(filename, name, r, filter, opts.warcRecordOption...)
 	if err != nil {
 		log.Error().Err(err).Msgf("Indexing failed: %s", filename)
 	}
 
 	log.Info().Msgf("Indexed %5d of %5d records in %10v: %s\n", count, total, time.Since(start), filename)
+
+	return err
 }
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package indextest provides an index of cdx records held in memory for tests of the servers.
+package indextest
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/timestamp"
+)
+
+// Response is the response of a cdx record, or of an error if Err is not nil.
+type Response struct {
+	Cdx *schema.Cdx
+	Err error
+}
+
+func (r Response) GetCdx() *schema.Cdx { return r.Cdx }
+
+func (r Response) GetError() error { return r.Err }
+
+// CdxAPI is an index of cdx records held in memory.
+//
+// A search matches records by the url of the request according to the match type,
+// where urls are matched regardless of scheme unless the match type is verbatim.
+// The matching records that pass the filter are sorted by url without scheme and
+// time, in reverse or by closeness, and then collapsed and limited.
+type CdxAPI []*schema.Cdx
+
+func (c CdxAPI) Search(ctx context.Context, req index.Request, res chan<- index.CdxResponse) error {
+	var closest int64
+	if req.Sort() == index.SortClosest {
+		t, err := timestamp.Parse(req.Closest())
+		if err != nil {
+			return err
+		}
+		closest = t.Unix()
+	}
+	var results []*schema.Cdx
+	for _, cdx := range c {
+		if !match(req, cdx.GetUri()) {
+			continue
+		}
+		if !req.Filter().Eval(cdx) {
+			continue
+		}
+		results = append(results, cdx)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := withoutScheme(results[i].GetUri()), withoutScheme(results[j].GetUri())
+		return a < b || a == b && results[i].GetSts().AsTime().Before(results[j].GetSts().AsTime())
+	})
+	switch req.Sort() {
+	case index.SortDesc:
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	case index.SortClosest:
+		distance := func(cdx *schema.Cdx) int64 {
+			d := cdx.GetSts().GetSeconds() - closest
+			if d < 0 {
+				return -d
+			}
+			return d
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return distance(results[i]) < distance(results[j])
+		})
+	}
+	collapser := req.Collapse()
+	go func() {
+		defer close(res)
+		count := 0
+		for _, cdx := range results {
+			if collapser != nil && collapser.Collapse(cdx) {
+				continue
+			}
+			select {
+			case res <- Response{Cdx: cdx}:
+			case <-ctx.Done():
+				return
+			}
+			count++
+			if req.Limit() > 0 && count >= req.Limit() {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// match returns true if uri is matched by the url and match type of req.
+func match(req index.Request, uri string) bool {
+	if req.MatchType() == index.MatchTypeVerbatim {
+		return uri == req.Url().String()
+	}
+	got, want := withoutScheme(uri), withoutScheme(req.Url().String())
+	switch req.MatchType() {
+	case index.MatchTypePrefix:
+		prefix, _, _ := strings.Cut(want, "?")
+		return strings.HasPrefix(got, prefix)
+	case index.MatchTypeDomain:
+		host, _, _ := strings.Cut(got, "/")
+		domain, _, _ := strings.Cut(want, "/")
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	default:
+		return got == want
+	}
+}
+
+func withoutScheme(uri string) string {
+	_, rest, _ := strings.Cut(uri, "://")
+	return rest
+}
+
+// FailingCdxAPI is a CdxAPI that responds with Err after the first After results of
+// a search of CdxAPI. The search ends with Err if it is caused by exceeding the budget.
+type FailingCdxAPI struct {
+	CdxAPI index.CdxAPI
+	After  int
+	Err    error
+}
+
+func (f FailingCdxAPI) Search(ctx context.Context, req index.Request, res chan<- index.CdxResponse) error {
+	response := make(chan index.CdxResponse)
+	if err := f.CdxAPI.Search(ctx, req, response); err != nil {
+		return err
+	}
+	go func() {
+		defer close(res)
+		count := 0
+		failed := false
+		for r := range response {
+			if !failed && count == f.After {
+				res <- Response{Err: f.Err}
+				failed = true
+			}
+			// a search that exceeds its budget ends, so the rest of the results are drained
+			if failed && index.IsBudgetExceeded(f.Err) {
+				continue
+			}
+			res <- r
+			count++
+		}
+		if !failed {
+			res <- Response{Err: f.Err}
+		}
+	}()
+	return nil
+}
@@ -20,7 +20,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"path/filepath"
 
 	"github.com/nlnwa/gowarc"
 	"github.com/rs/zerolog/log"
@@ -32,10 +31,9 @@ type RecordWriter interface {
 	Write(Record) error
 }
 
-// readFile reads, filters and writes records of a warc file to a record writer
-func readFile(path string, writer RecordWriter, filter recordFilter, opts ...gowarc.WarcRecordOption) (int, int, error) {
-	filename := filepath.Base(path)
-
+// readFile reads, filters and writes records of a warc file to a record writer.
+// The records refer to the file by filename.
+func readFile(path string, filename string, writer RecordWriter, filter recordFilter, opts ...gowarc.WarcRecordOption) (int, int, error) {
 	wf, err := gowarc.NewWarcFileReader(path, 0, opts...)
 	if err != nil {
 		return 0, 0, err
@@ -53,10 +51,13 @@ func readFile(path string, writer RecordWriter, filter recordFilter, opts ...gow
 	for {
 		wr, offset, validation, err := wf.Next()
 		if prevWr != nil {
-			if r, err := newRecord(prevWr, filename, prevOffset, offset-prevOffset); err != nil {
+			r, err := newRecord(prevWr, filename, prevOffset, offset-prevOffset)
+			r.file = path
+			// unparseable records are skipped, but a failed write fails the file so that it is not committed
+			if err != nil {
 				log.Error().Err(err).Msgf("Failed to create index record %s#%d", filename, prevOffset)
 			} else if err = writer.Write(r); err != nil {
-				log.Error().Err(err).Msgf("Failed to index record: %s#%d", filename, prevOffset)
+				return count, total, fmt.Errorf("failed to index record %s#%d: %w", filename, prevOffset, err)
 			} else {
 				count++
 			}
@@ -17,6 +17,7 @@
 package index
 
 import (
+	"errors"
 	"os"
 	"path"
 	"testing"
@@ -70,10 +71,110 @@ Content-Length: 0`)
 
 	for _, tt := range tests {
 		t.Run(tt.format, func(t *testing.T) {
-			_, _, err = readFile(filepath, tt.writer, func(gowarc.WarcRecord, *gowarc.Validation) bool { return true })
+			_, _, err = readFile(filepath, "test.warc", tt.writer, func(gowarc.WarcRecord, *gowarc.Validation) bool { return true })
 			if err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
 		})
 	}
 }
+
+type recordCollector []Record
+
+func (rc *recordCollector) Write(rec Record) error {
+	*rc = append(*rc, rec)
+	return nil
+}
+
+func TestRecordTypePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		recordTypes []string
+		want        []string
+		wantMct     []string
+	}{
+		{
+			name: "default",
+			path: "../testdata/example.warc.gz",
+			want: []string{"response", "revisit"},
+		},
+		{
+			name:        "request",
+			path:        "../testdata/example.warc.gz",
+			recordTypes: []string{"response", "revisit", "request"},
+			want:        []string{"response", "request", "revisit", "request"},
+		},
+		{
+			name:        "resource",
+			path:        "../testdata/example-resource.warc.gz",
+			recordTypes: []string{"resource"},
+			want:        []string{"resource"},
+			wantMct:     []string{"text/html; charset=utf-8"},
+		},
+		{
+			name: "resource not indexed by default",
+			path: "../testdata/example-resource.warc.gz",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, err := ParseRecordTypes(tt.recordTypes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &Options{RecordTypes: rt}
+			filter := func(wr gowarc.WarcRecord, _ *gowarc.Validation) bool {
+				return opts.accept(wr)
+			}
+			var records recordCollector
+			_, _, err = readFile(tt.path, path.Base(tt.path), &records, filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(records) != len(tt.want) {
+				t.Fatalf("got %d records, want %d", len(records), len(tt.want))
+			}
+			for i, rec := range records {
+				if rec.Srt != tt.want[i] {
+					t.Errorf("[%d] got record type %s, want %s", i, rec.Srt, tt.want[i])
+				}
+				if rec.Ssu != "com,example,//:http:/" {
+					t.Errorf("[%d] got ssurt %s, want %s", i, rec.Ssu, "com,example,//:http:/")
+				}
+				if i < len(tt.wantMct) && rec.Mct != tt.wantMct[i] {
+					t.Errorf("[%d] got content type %s, want %s", i, rec.Mct, tt.wantMct[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRecordTypes(t *testing.T) {
+	rt, err := ParseRecordTypes([]string{"response", "Resource"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rt != gowarc.Response|gowarc.Resource {
+		t.Errorf("got %d, want %d", rt, gowarc.Response|gowarc.Resource)
+	}
+	if _, err := ParseRecordTypes([]string{"warcinfo"}); err == nil {
+		t.Error("expected error for record type warcinfo")
+	}
+}
+
+// failingWriter fails to write every record.
+type failingWriter struct{}
+
+func (failingWriter) Write(Record) error {
+	return errors.New("database is closed")
+}
+
+func TestReadFileWriteError(t *testing.T) {
+	_, _, err := readFile("../testdata/example.warc", "example.warc", failingWriter{}, func(gowarc.WarcRecord, *gowarc.Validation) bool { return true })
+	if err == nil {
+		t.Error("expected error when records fail to be written")
+	}
+}
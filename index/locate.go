@@ -0,0 +1,130 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// FileLocator returns the path and identity of the file described by fileInfo,
+// or an empty path if there is no such file.
+type FileLocator func(fileInfo *schema.FileInfo) (path string, name string, err error)
+
+// NewFileLocator returns a FileLocator that searches roots for moved files.
+//
+// A file is first looked for by its identity directly below each root, and if
+// not found there the roots are walked down to maxDepth for files with the same
+// filename, size and modification time. The identity of a file found by the walk
+// may differ from the indexed one, e.g. when identified by path and moved to another
+// directory. The walk is only done once, the first time it is needed.
+//
+// It is an error if a root does not exist, because the root might not be mounted
+// and then every file below it would wrongly be considered missing.
+func NewFileLocator(identity FileIdentity, roots []string, maxDepth int) FileLocator {
+	// found maps filenames to the paths of the files with that name
+	var found map[string][]string
+
+	walk := func() error {
+		found = make(map[string][]string)
+		var walkDir func(dir string, depth int) error
+		walkDir = func(dir string, depth int) error {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return fmt.Errorf(`failed to read directory "%s": %w`, dir, err)
+			}
+			for _, entry := range entries {
+				path := filepath.Join(dir, entry.Name())
+				if entry.IsDir() {
+					if depth < maxDepth {
+						if err := walkDir(path, depth+1); err != nil {
+							return err
+						}
+					}
+					continue
+				}
+				found[entry.Name()] = append(found[entry.Name()], path)
+			}
+			return nil
+		}
+		for _, root := range roots {
+			if err := walkDir(root, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return func(fileInfo *schema.FileInfo) (string, string, error) {
+		name := fileInfo.GetName()
+		for _, root := range roots {
+			if _, err := os.Stat(root); err != nil {
+				return "", "", fmt.Errorf("root is not available: %w", err)
+			}
+			// the name may be relative to the root, or to its parent if prefixed by the name of the root
+			for _, dir := range []string{root, filepath.Dir(root)} {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if !isSameFile(path, fileInfo) {
+					continue
+				}
+				if id, err := identity(path); err == nil && id == name {
+					path, err := filepath.Abs(path)
+					return path, name, err
+				}
+			}
+		}
+		if found == nil {
+			if err := walk(); err != nil {
+				found = nil
+				return "", "", err
+			}
+		}
+		var match string
+		for _, path := range found[filepath.Base(fileInfo.GetPath())] {
+			if !isSameFile(path, fileInfo) {
+				continue
+			}
+			if match != "" {
+				log.Warn().Msgf("Found multiple files matching %s: %s and %s", name, match, path)
+				continue
+			}
+			match = path
+		}
+		if match == "" {
+			return "", "", nil
+		}
+		id, err := identity(match)
+		if err != nil {
+			return "", "", err
+		}
+		path, err := filepath.Abs(match)
+		return path, id, err
+	}
+}
+
+// isSameFile returns true if path is a regular file with the size and modification time of fileInfo.
+func isSameFile(path string, fileInfo *schema.FileInfo) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Size() == fileInfo.GetSize() && info.ModTime().Equal(fileInfo.GetLastModified().AsTime())
+}
@@ -17,14 +17,47 @@
 package index
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/nlnwa/gowarc"
 )
 
+// DefaultRecordTypes are the record types indexed when no record types are configured.
+const DefaultRecordTypes = gowarc.Response | gowarc.Revisit
+
+// recordTypes maps the names of the indexable record types to their gowarc.RecordType.
+//
+// Warcinfo and continuation records are not indexable because they have no target URI of their own.
+var recordTypes = map[string]gowarc.RecordType{
+	gowarc.Response.String():   gowarc.Response,
+	gowarc.Revisit.String():    gowarc.Revisit,
+	gowarc.Resource.String():   gowarc.Resource,
+	gowarc.Request.String():    gowarc.Request,
+	gowarc.Metadata.String():   gowarc.Metadata,
+	gowarc.Conversion.String(): gowarc.Conversion,
+}
+
+// ParseRecordTypes parses a list of record type names (e.g. "response", "resource") into a record type bit mask.
+func ParseRecordTypes(names []string) (gowarc.RecordType, error) {
+	var rt gowarc.RecordType
+	for _, name := range names {
+		t, ok := recordTypes[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unsupported record type: %s", name)
+		}
+		rt |= t
+	}
+	return rt, nil
+}
+
 type Options struct {
 	Includes         []*regexp.Regexp
 	Excludes         []*regexp.Regexp
+	RecordTypes      gowarc.RecordType
+	NonHttp          bool
+	identity         FileIdentity
 	warcRecordOption []gowarc.WarcRecordOption
 }
 
@@ -55,6 +88,26 @@ func (o *Options) isIncluded(name string) bool {
 	return false
 }
 
+// accept returns true if the record should be indexed according to the record type policy.
+//
+// Response, revisit and request records are only accepted if they are of
+// content type application/http, unless indexing of non-HTTP records is enabled.
+func (o *Options) accept(wr gowarc.WarcRecord) bool {
+	rt := o.RecordTypes
+	if rt == 0 {
+		rt = DefaultRecordTypes
+	}
+	if wr.Type()&rt == 0 {
+		return false
+	}
+	// nolint:exhaustive
+	switch wr.Type() {
+	case gowarc.Response, gowarc.Revisit, gowarc.Request:
+		return o.NonHttp || strings.HasPrefix(wr.WarcHeader().Get(gowarc.ContentType), gowarc.ApplicationHttp)
+	}
+	return true
+}
+
 func WithIncludes(res ...*regexp.Regexp) Option {
 	return func(opts *Options) {
 		opts.Includes = res
@@ -66,3 +119,24 @@ func WithExcludes(res ...*regexp.Regexp) Option {
 		opts.Excludes = res
 	}
 }
+
+// WithRecordTypes sets the record types to index.
+func WithRecordTypes(rt gowarc.RecordType) Option {
+	return func(opts *Options) {
+		opts.RecordTypes = rt
+	}
+}
+
+// WithNonHttp enables indexing of response, revisit and request records that are not of content type application/http (e.g. dns).
+func WithNonHttp(nonHttp bool) Option {
+	return func(opts *Options) {
+		opts.NonHttp = nonHttp
+	}
+}
+
+// WithFileIdentity sets how files are identified in storage refs.
+func WithFileIdentity(identity FileIdentity) Option {
+	return func(opts *Options) {
+		opts.identity = identity
+	}
+}
@@ -37,12 +37,19 @@ import (
 
 type Record struct {
 	*schema.Cdx
+	// file is the path of the file the record was read from
+	file string
 }
 
 func (r Record) String() string {
 	return fmt.Sprintf("%s %s", r.Ref, r.Uri)
 }
 
+// File returns the path of the file the record was read from.
+func (r Record) File() string {
+	return r.file
+}
+
 // newRecord constructs a Record from wr, filename, offset and length.
 func newRecord(wr gowarc.WarcRecord, filename string, offset int64, length int64) (rec Record, err error) {
 	cle, err := wr.WarcHeader().GetInt64(gowarc.ContentLength)
@@ -75,12 +82,36 @@ func newRecord(wr gowarc.WarcRecord, filename string, offset int64, length int64
 	// nolint:exhaustive
 	switch wr.Type() {
 	case gowarc.Response:
-		if block, ok := wr.Block().(gowarc.HttpResponseBlock); ok {
-			header := block.HttpHeader()
-			if header == nil {
-				return
+		block, ok := wr.Block().(gowarc.HttpResponseBlock)
+		if !ok {
+			// non-HTTP response (e.g. dns) where the whole block is the payload
+			rec.Mct = wr.WarcHeader().Get(gowarc.ContentType)
+			rec.Ple = cle
+			return
+		}
+		header := block.HttpHeader()
+		if header == nil {
+			return
+		}
+		rec.Hsc = int32(block.HttpStatusCode())
+		rec.Mct = header.Get("Content-Type")
+		cl := header.Get("Content-Length")
+		if len(cl) > 0 {
+			var err error
+			rec.Ple, err = strconv.ParseInt(cl, 10, 64)
+			if err != nil {
+				log.Warn().Msgf("Failed to parse HTTP header field 'Content-Length' as int64: %v", err)
 			}
-			rec.Hsc = int32(block.HttpStatusCode())
+		}
+	case gowarc.Request:
+		block, ok := wr.Block().(gowarc.HttpRequestBlock)
+		if !ok {
+			rec.Mct = wr.WarcHeader().Get(gowarc.ContentType)
+			rec.Ple = cle
+			return
+		}
+		header := block.HttpHeader()
+		if header != nil {
 			rec.Mct = header.Get("Content-Type")
 			cl := header.Get("Content-Length")
 			if len(cl) > 0 {
@@ -91,6 +122,10 @@ func newRecord(wr gowarc.WarcRecord, filename string, offset int64, length int64
 				}
 			}
 		}
+	case gowarc.Resource, gowarc.Metadata, gowarc.Conversion:
+		// the whole block is the payload of these record types
+		rec.Mct = wr.WarcHeader().Get(gowarc.ContentType)
+		rec.Ple = cle
 	case gowarc.Revisit:
 		rec.Rou = wr.WarcHeader().Get(gowarc.WarcRefersToTargetURI)
 		if t, err := wr.WarcHeader().GetTime(gowarc.WarcRefersToDate); err == nil {
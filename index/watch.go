@@ -0,0 +1,235 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDebounce is how long a file must be left unchanged before it is indexed by the WatchIndexer.
+const DefaultDebounce = 10 * time.Second
+
+func WithDebounce(d time.Duration) AutoIndexOption {
+	return func(opts *AutoIndexOptions) {
+		opts.Debounce = d
+	}
+}
+
+// WatchIndexer indexes the files found in the configured paths and then
+// keeps watching the directories for files that are created, written to or
+// renamed into place.
+//
+// A file is only indexed when it has not changed for the debounce duration,
+// so that files still being written are not indexed prematurely.
+type WatchIndexer struct {
+	AutoIndexer
+}
+
+func NewWatchIndexer(queue Queue, options ...AutoIndexOption) WatchIndexer {
+	a := NewAutoIndexer(queue, options...)
+	if a.opts.Debounce <= 0 {
+		a.opts.Debounce = DefaultDebounce
+	}
+	return WatchIndexer{AutoIndexer: a}
+}
+
+// watchState is the state of a running WatchIndexer.
+type watchState struct {
+	watcher *fsnotify.Watcher
+	// roots are the configured directories being watched
+	roots []string
+	// depths maps the watched directories to their directory recursion depth
+	depths map[string]int
+	// pending maps the files waiting to be indexed to the time they were last changed
+	pending map[string]time.Time
+	// due are the files ready to be handed to the queue, in order
+	due []string
+}
+
+func (w WatchIndexer) Run(ctx context.Context) error {
+	w.done = ctx.Done()
+	defer w.queue.Close()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	s := &watchState{
+		watcher: watcher,
+		depths:  make(map[string]int),
+		pending: make(map[string]time.Time),
+	}
+
+	// the queue blocks while the workers are busy, so files are handed to it
+	// from a separate goroutine to keep the event loop draining the watcher
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for path := range queue {
+			w.enqueue(path)
+		}
+	}()
+	defer wg.Wait()
+	defer close(queue)
+
+	// watch the directories while walking them so that no files are missed,
+	// the files found are indexed without waiting for the debounce duration
+	for _, path := range w.opts.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Warn().Msgf(`Error indexing "%s": %v`, path, err)
+			continue
+		}
+		if !info.IsDir() {
+			s.due = append(s.due, path)
+			continue
+		}
+		root := filepath.Clean(path)
+		s.roots = append(s.roots, root)
+		if err := w.watch(s, root, 0, s.addDue); err != nil {
+			log.Warn().Msgf(`Error watching "%s": %v`, path, err)
+		}
+	}
+
+	ticker := time.NewTicker(w.opts.Debounce / 2)
+	defer ticker.Stop()
+
+	for {
+		var next string
+		var out chan<- string
+		if len(s.due) > 0 {
+			next = s.due[0]
+			out = queue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case out <- next:
+			s.due = s.due[1:]
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(s, event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Warn().Err(err).Msg("File watcher error")
+				continue
+			}
+			// events have been dropped, so rescan the watched directories
+			log.Warn().Err(err).Msg("File watcher overflow, rescanning watched directories")
+			for _, root := range s.roots {
+				if err := w.watch(s, root, 0, s.addPending); err != nil {
+					log.Warn().Msgf(`Error watching "%s": %v`, root, err)
+				}
+			}
+		case now := <-ticker.C:
+			for path, changed := range s.pending {
+				if now.Sub(changed) >= w.opts.Debounce {
+					delete(s.pending, path)
+					s.due = append(s.due, path)
+				}
+			}
+		}
+	}
+}
+
+// addDue adds path to the files ready to be indexed.
+func (s *watchState) addDue(path string) {
+	s.due = append(s.due, path)
+}
+
+// addPending adds path to the files waiting for the debounce duration.
+func (s *watchState) addPending(path string) {
+	s.pending[path] = time.Now()
+}
+
+// watch adds dir and its subdirectories down to the max depth to the watcher.
+// The files found are passed to add.
+func (w WatchIndexer) watch(s *watchState, dir string, depth int, add func(path string)) error {
+	if w.opts.isExcluded(dir) {
+		return nil
+	}
+	if err := s.watcher.Add(dir); err != nil {
+		return fmt.Errorf(`failed to watch directory "%s": %w`, dir, err)
+	}
+	s.depths[dir] = depth
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf(`failed to read directory "%s": %w`, dir, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() {
+			add(path)
+		} else if depth < w.opts.MaxDepth {
+			if err := w.watch(s, path, depth+1, add); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handle updates the watched directories and pending files according to event.
+func (w WatchIndexer) handle(s *watchState, event fsnotify.Event) {
+	path := event.Name
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		if !info.IsDir() {
+			s.addPending(path)
+			return
+		}
+		depth, ok := s.depths[filepath.Dir(path)]
+		if !ok || depth >= w.opts.MaxDepth {
+			return
+		}
+		// a directory that is created or renamed into place may already contain files
+		if err := w.watch(s, path, depth+1, s.addPending); err != nil {
+			log.Warn().Err(err).Msgf("Failed to watch new directory: %s", path)
+		}
+	case event.Has(fsnotify.Write):
+		if _, ok := s.depths[path]; !ok {
+			s.addPending(path)
+		}
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		delete(s.pending, path)
+		delete(s.depths, path)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type pathQueue struct {
+	mu     sync.Mutex
+	paths  []string
+	closed chan struct{}
+}
+
+func (q *pathQueue) Add(path string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.paths = append(q.paths, path)
+}
+
+func (q *pathQueue) Close() {
+	close(q.closed)
+}
+
+func (q *pathQueue) Paths() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	paths := append([]string(nil), q.paths...)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWatchIndexer(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "existing.warc.gz"))
+
+	queue := &pathQueue{closed: make(chan struct{})}
+	w := NewWatchIndexer(queue,
+		WithPaths([]string{dir}),
+		WithMaxDepth(1),
+		WithDebounce(100*time.Millisecond),
+		WithExcludeDirs(regexp.MustCompile("excluded$")),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	// wait for the initial walk
+	waitFor(t, func() bool { return len(queue.Paths()) == 1 })
+
+	writeFile(t, filepath.Join(dir, "new.warc.gz"))
+	writeFile(t, filepath.Join(dir, "new.warc.gz.open"))
+	if err := os.Rename(filepath.Join(dir, "new.warc.gz.open"), filepath.Join(dir, "renamed.warc.gz")); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"a", "a/b", "excluded"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// give the watcher time to watch the new directories
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "a", "nested.warc.gz"))
+	writeFile(t, filepath.Join(dir, "a", "b", "too-deep.warc.gz"))
+	writeFile(t, filepath.Join(dir, "excluded", "excluded.warc.gz"))
+
+	want := []string{
+		filepath.Join(dir, "a", "nested.warc.gz"),
+		filepath.Join(dir, "existing.warc.gz"),
+		filepath.Join(dir, "new.warc.gz"),
+		filepath.Join(dir, "renamed.warc.gz"),
+	}
+	waitFor(t, func() bool { return len(queue.Paths()) >= len(want) })
+	// wait for any unexpected files to be enqueued
+	time.Sleep(300 * time.Millisecond)
+
+	cancel()
+	<-queue.closed
+
+	got := queue.Paths()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+// blockingQueue is a pathQueue that blocks adding paths until it is released.
+type blockingQueue struct {
+	pathQueue
+	release chan struct{}
+}
+
+func (q *blockingQueue) Add(path string) {
+	<-q.release
+	q.pathQueue.Add(path)
+}
+
+func TestWatchIndexerBlockedQueue(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.warc.gz", "b.warc.gz"} {
+		writeFile(t, filepath.Join(dir, name))
+	}
+
+	queue := &blockingQueue{
+		pathQueue: pathQueue{closed: make(chan struct{})},
+		release:   make(chan struct{}),
+	}
+	w := NewWatchIndexer(queue,
+		WithPaths([]string{dir}),
+		WithDebounce(100*time.Millisecond),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	// files written while the queue is blocked must not be missed
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "c.warc.gz"))
+	time.Sleep(200 * time.Millisecond)
+	close(queue.release)
+
+	want := []string{
+		filepath.Join(dir, "a.warc.gz"),
+		filepath.Join(dir, "b.warc.gz"),
+		filepath.Join(dir, "c.warc.gz"),
+	}
+	waitFor(t, func() bool { return len(queue.Paths()) >= len(want) })
+
+	cancel()
+	<-queue.closed
+
+	got := queue.Paths()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("WARC/1.1\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
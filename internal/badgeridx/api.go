@@ -17,13 +17,16 @@
 package badgeridx
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/nlnwa/gowarcserver/index"
 	"github.com/nlnwa/gowarcserver/internal/keyvalue"
 	"github.com/nlnwa/gowarcserver/loader"
 	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
 	"github.com/nlnwa/gowarcserver/timestamp"
 	"google.golang.org/protobuf/proto"
 )
@@ -42,6 +45,21 @@ var _ index.FileAPI = (*DB)(nil)
 // Assert DB implements the index.IdAPI interface.
 var _ index.IdAPI = (*DB)(nil)
 
+// Assert that DB implements index.Committer
+var _ index.Committer = (*DB)(nil)
+
+// Assert that DB implements index.FileIdentityMigrator
+var _ index.FileIdentityMigrator = (*DB)(nil)
+
+// Assert that DB implements index.HistogramAPI
+var _ index.HistogramAPI = (*DB)(nil)
+
+// Assert DB implements the keyvalue.UrlAPI interface.
+var _ keyvalue.UrlAPI = (*DB)(nil)
+
+// Assert that DB implements index.Reconciler
+var _ index.Reconciler = (*DB)(nil)
+
 // Assert that DB implements index.ReportGenerator
 var _ index.ReportGenerator = (*DB)(nil)
 
@@ -69,6 +87,7 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, results chan
 
 	dateRange := req.DateRange()
 	filter := req.Filter()
+	budget := req.Budget()
 
 	go func() {
 		_ = db.CdxIndex.View(func(txn *badger.Txn) error {
@@ -84,9 +103,19 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, results chan
 			for it.Seek(key); it.ValidForPrefix(key); it.Next() {
 				cdxResponse := func() (cdxResponse *keyvalue.CdxResponse) {
 					key := keyvalue.CdxKey(it.Item().KeyCopy(nil))
+					if budget != nil {
+						if err := budget.ScanKey(); err != nil {
+							return &keyvalue.CdxResponse{Error: err}
+						}
+					}
 					if !dateRange.Contains(key.Unix()) {
 						return nil
 					}
+					if budget != nil {
+						if err := budget.DecodeValue(); err != nil {
+							return &keyvalue.CdxResponse{Error: err}
+						}
+					}
 					err := it.Item().Value(func(v []byte) error {
 						result := new(schema.Cdx)
 						if err := proto.Unmarshal(v, result); err != nil {
@@ -120,6 +149,10 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, results chan
 						count++
 					}
 				}
+				// stop iteration if the budget is exceeded
+				if index.IsBudgetExceeded(cdxResponse.GetError()) {
+					break
+				}
 				// stop iteration if limit is reached
 				if req.Limit() > 0 && count >= req.Limit() {
 					break
@@ -146,7 +179,8 @@ func (db *DB) closest(ctx context.Context, request index.Request, results chan<-
 	closest := ts.Unix()
 	isClosest := timestamp.CompareClosest(closest)
 	matchType := request.MatchType()
-	_, portSchemeUserInfo, _ := keyvalue.SplitSSURT(request.Ssurt())
+	budget := request.Budget()
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(request.Ssurt())
 
 	go func() {
 		defer close(results)
@@ -195,6 +229,12 @@ func (db *DB) closest(ctx context.Context, request index.Request, results chan<-
 					return nil
 				}
 				key := keyvalue.CdxKey(iter.Item().Key())
+				if budget != nil {
+					if err := budget.ScanKey(); err != nil {
+						results <- keyvalue.CdxResponse{Error: err}
+						return nil
+					}
+				}
 				if matchType == index.MatchTypeVerbatim {
 					if key.PortSchemeUserInfo() != portSchemeUserInfo {
 						iter.Next()
@@ -202,6 +242,12 @@ func (db *DB) closest(ctx context.Context, request index.Request, results chan<-
 					}
 				}
 				var cdxResponse keyvalue.CdxResponse
+				if budget != nil {
+					if err := budget.DecodeValue(); err != nil {
+						results <- keyvalue.CdxResponse{Error: err}
+						return nil
+					}
+				}
 				cdx, err := cdxFromItem(iter.Item())
 				if err != nil {
 					cdxResponse = keyvalue.CdxResponse{Error: err}
@@ -221,6 +267,10 @@ func (db *DB) closest(ctx context.Context, request index.Request, results chan<-
 						count++
 					}
 				}
+				// stop iteration if the budget is exceeded
+				if index.IsBudgetExceeded(cdxResponse.GetError()) {
+					break
+				}
 				if request.Limit() > 0 && count >= request.Limit() {
 					break
 				}
@@ -242,7 +292,7 @@ func (db *DB) Search(ctx context.Context, search index.Request, results chan<- i
 
 // search the index database.
 func (db *DB) search(ctx context.Context, req index.Request, results chan<- index.CdxResponse) error {
-	_, portSchemeUserInfo, _ := keyvalue.SplitSSURT(req.Ssurt())
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
 	reverse := req.Sort() == index.SortDesc
 	prefix := keyvalue.SearchKey(req)
 
@@ -251,8 +301,19 @@ func (db *DB) search(ctx context.Context, req index.Request, results chan<- inde
 	if reverse {
 		key = append(key, 0xff)
 	}
+	// continue after the resume key
+	var resumeKey []byte
+	if req.ResumeKey() != "" {
+		resumeKey = []byte(req.ResumeKey())
+		if !bytes.HasPrefix(resumeKey, prefix) {
+			return fmt.Errorf("%w: %s", index.InvalidResumeKeyError, resumeKey)
+		}
+		key = resumeKey
+	}
 	dateRange := req.DateRange()
 	filter := req.Filter()
+	budget := req.Budget()
+	collapser := req.Collapse()
 	matchType := req.MatchType()
 
 	go func() {
@@ -267,9 +328,26 @@ func (db *DB) search(ctx context.Context, req index.Request, results chan<- inde
 			defer it.Close()
 			defer close(results)
 
-			for it.Seek(key); it.ValidForPrefix(prefix); it.Next() {
+			it.Seek(key)
+			// the resume key was the last result of the previous search
+			if resumeKey != nil && it.ValidForPrefix(prefix) && bytes.Equal(it.Item().Key(), resumeKey) {
+				// collapse results equal to the last result of the previous search
+				if collapser != nil {
+					if cdx, err := cdxFromItem(it.Item()); err == nil {
+						collapser.Collapse(cdx)
+					}
+				}
+				it.Next()
+			}
+
+			for ; it.ValidForPrefix(prefix); it.Next() {
 				cdxResponse := func() (cdxResponse *keyvalue.CdxResponse) {
 					key := keyvalue.CdxKey(it.Item().KeyCopy(nil))
+					if budget != nil {
+						if err := budget.ScanKey(); err != nil {
+							return &keyvalue.CdxResponse{Error: err}
+						}
+					}
 					if !dateRange.Contains(key.Unix()) {
 						return nil
 					}
@@ -278,12 +356,17 @@ func (db *DB) search(ctx context.Context, req index.Request, results chan<- inde
 							return nil
 						}
 					}
+					if budget != nil {
+						if err := budget.DecodeValue(); err != nil {
+							return &keyvalue.CdxResponse{Error: err}
+						}
+					}
 					err := it.Item().Value(func(v []byte) error {
 						result := new(schema.Cdx)
 						if err := proto.Unmarshal(v, result); err != nil {
 							return err
 						}
-						if filter.Eval(result) {
+						if filter.Eval(result) && (collapser == nil || !collapser.Collapse(result)) {
 							cdxResponse = &keyvalue.CdxResponse{
 								Key:   key,
 								Value: result,
@@ -311,6 +394,10 @@ func (db *DB) search(ctx context.Context, req index.Request, results chan<- inde
 						count++
 					}
 				}
+				// stop iteration if the budget is exceeded
+				if index.IsBudgetExceeded(cdxResponse.GetError()) {
+					break
+				}
 				// stop iteration if limit is reached
 				if req.Limit() > 0 && count >= req.Limit() {
 					break
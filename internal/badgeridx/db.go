@@ -40,7 +40,7 @@ type DB struct {
 	// IdIndex maps record id to storage ref
 	IdIndex *badger.DB
 
-	// FileIndex maps filepath to fileinfo
+	// FileIndex maps file identity to fileinfo
 	FileIndex *badger.DB
 
 	// CdxIndex maps cdx key to cdx record
@@ -51,6 +51,15 @@ type DB struct {
 
 	batch chan index.Record
 
+	// commits keeps track of files being indexed
+	commits *keyvalue.FileCommits
+
+	// flushMu serializes batch flushes
+	flushMu sync.Mutex
+
+	// identity identifies files in the file index
+	identity index.FileIdentity
+
 	done chan struct{}
 
 	wg sync.WaitGroup
@@ -95,6 +104,8 @@ func NewDB(options ...Option) (db *DB, err error) {
 		CdxIndex:    cdxIndex,
 		ReportIndex: reportIndex,
 		batch:       batch,
+		commits:     keyvalue.NewFileCommits(),
+		identity:    opts.FileIdentity,
 		done:        done,
 		tasks:       make(map[string]context.CancelFunc),
 	}
@@ -108,7 +119,7 @@ func NewDB(options ...Option) (db *DB, err error) {
 	db.wg.Add(1)
 	go func() {
 		defer db.wg.Done()
-		ticker := time.NewTimer(opts.BatchMaxWait)
+		ticker := time.NewTicker(opts.BatchMaxWait)
 		defer ticker.Stop()
 		for {
 			select {
@@ -173,7 +184,8 @@ func (db *DB) Close() {
 	_ = db.ReportIndex.Close()
 }
 
-// addFile checks if file is indexed or has not changed since indexing, and adds file to file index.
+// addFile checks if file is indexed or has not changed since indexing, and starts tracking the file
+// so that it can be added to the file index when all of its records have been written.
 func (db *DB) addFile(path string) error {
 	stat, err := os.Stat(path)
 	if err != nil {
@@ -182,7 +194,10 @@ func (db *DB) addFile(path string) error {
 
 	fileSize := stat.Size()
 	fileLastModified := stat.ModTime()
-	fn := filepath.Base(path)
+	fn, err := db.identity(path)
+	if err != nil {
+		return err
+	}
 	if fileInfo, err := db.getFileInfo(fn); err == nil {
 		if err := fileInfo.GetLastModified().CheckValid(); err != nil {
 			return err
@@ -193,27 +208,36 @@ func (db *DB) addFile(path string) error {
 		}
 	}
 
-	return db.updateFilePath(path)
+	fileInfo, err := newFileInfo(path, fn)
+	if err != nil {
+		return err
+	}
+	return db.commits.Begin(path, fileInfo)
 }
 
-func (db *DB) updateFilePath(path string) error {
+// newFileInfo returns the file info of the file at path identified by name.
+func newFileInfo(path string, name string) (*schema.FileInfo, error) {
 	var err error
 	fileInfo := &schema.FileInfo{}
 
 	fileInfo.Path, err = filepath.Abs(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fileInfo.Name = filepath.Base(fileInfo.Path)
+	fileInfo.Name = name
 	stat, err := os.Stat(fileInfo.Path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	fileInfo.Size = stat.Size()
 	fileInfo.LastModified = timestamppb.New(stat.ModTime())
 
+	return fileInfo, nil
+}
+
+func (db *DB) putFileInfo(fileInfo *schema.FileInfo) error {
 	key, value, err := keyvalue.MarshalFileInfo(fileInfo, "")
 	if err != nil {
 		return err
@@ -228,7 +252,8 @@ func (db *DB) updateFilePath(path string) error {
 func (db *DB) write(rec index.Record) {
 	select {
 	case <-db.done:
-		// do nothing
+		// record is dropped so the file must be indexed again
+		db.commits.Fail(rec.File())
 	case db.batch <- rec:
 		// added record to batch
 	default:
@@ -251,20 +276,50 @@ func (db *DB) collectBatch() (records []index.Record) {
 }
 
 // FlushBatch collects all records in the batch channel and updates the id and cdx indices.
+// Files with all records written are then added to the file index.
 func (db *DB) FlushBatch() {
-	records := db.collectBatch()
+	db.flushMu.Lock()
+	defer db.flushMu.Unlock()
+
+	// files must be collected before the batch to ensure all their records are in the batch
+	ready := db.commits.Ready()
+
+	db.writeBatch(db.collectBatch())
+
+	for _, path := range ready {
+		fileInfo, err := db.commits.Done(path)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Not marking file as indexed, it will be indexed again on next run: %s", path)
+			continue
+		}
+		if err := db.putFileInfo(fileInfo); err != nil {
+			log.Error().Err(err).Msgf("Failed to update file index: %s", path)
+		}
+	}
+}
+
+// writeBatch updates the id and cdx indices with records.
+func (db *DB) writeBatch(records []index.Record) {
 	if len(records) == 0 {
 		return
 	}
 
-	// update id index
+	failed := false
 
+	// update id index
 	if err := db.IdIndex.Update(set(records, marshalId)); err != nil {
 		log.Error().Err(err).Msgf("Failed to update id index")
+		failed = true
 	}
 	// update cdx index
 	if err := db.CdxIndex.Update(set(records, marshalCdx)); err != nil {
 		log.Error().Err(err).Msgf("Failed to update cdx index")
+		failed = true
+	}
+	if failed {
+		for _, r := range records {
+			db.commits.Fail(r.File())
+		}
 	}
 }
 
@@ -301,6 +356,16 @@ func (db *DB) Index(path string) error {
 	return db.addFile(path)
 }
 
+// Commit marks the file at path as indexed when all of its records have been written.
+func (db *DB) Commit(path string) error {
+	return db.commits.Commit(path)
+}
+
+// Abort stops tracking the file at path without marking it as indexed.
+func (db *DB) Abort(path string) {
+	db.commits.Abort(path)
+}
+
 // Resolve looks up warcId in the id index of the database and returns corresponding storageRef, or an error if not found.
 func (db *DB) Resolve(_ context.Context, warcId string) (storageRef string, err error) {
 	key := keyvalue.Key(warcId)
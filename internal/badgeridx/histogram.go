@@ -0,0 +1,90 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgeridx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/surt"
+)
+
+// Histogram counts the captures matching req by the first n digits of their timestamps.
+//
+// Only keys are read unless the request has a filter. If the budget of the
+// request is exceeded the captures counted so far are returned with the error.
+func (db *DB) Histogram(ctx context.Context, req index.Request, n int) (map[string]uint64, error) {
+	if n < 1 || n > 14 {
+		return nil, fmt.Errorf("invalid number of timestamp digits: %d", n)
+	}
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
+	prefix := keyvalue.SearchKey(req)
+	dateRange := req.DateRange()
+	filter := req.Filter()
+	keysOnly := index.IsEmptyFilter(filter)
+	budget := req.Budget()
+	matchType := req.MatchType()
+
+	histogram := make(map[string]uint64)
+	err := db.CdxIndex.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if budget != nil {
+				if err := budget.ScanKey(); err != nil {
+					return err
+				}
+			}
+			key := keyvalue.CdxKey(it.Item().Key())
+			if !dateRange.Contains(key.Unix()) {
+				continue
+			}
+			if matchType == index.MatchTypeVerbatim && key.PortSchemeUserInfo() != portSchemeUserInfo {
+				continue
+			}
+			if !keysOnly {
+				if budget != nil {
+					if err := budget.DecodeValue(); err != nil {
+						return err
+					}
+				}
+				cdx, err := cdxFromItem(it.Item())
+				if err != nil {
+					return err
+				}
+				if !filter.Eval(cdx) {
+					continue
+				}
+			}
+			ts := key.Timestamp()
+			histogram[ts[:min(n, len(ts))]]++
+		}
+		return nil
+	})
+	return histogram, err
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgeridx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// MigrateFileIdentity changes the identity of the files in the file index and
+// rewrites the storage refs of the id and cdx indices accordingly.
+//
+// The file infos are added under their new identity before the storage refs are
+// rewritten, and the old file infos are deleted last, so that every storage ref
+// can be resolved during the migration and an interrupted migration can be run again.
+func (db *DB) MigrateFileIdentity(ctx context.Context, identity index.FileIdentity) error {
+	renames := make(map[string]string)
+	var fileInfos []*schema.FileInfo
+
+	err := db.FileIndex.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			fileInfo := new(schema.FileInfo)
+			err := iter.Item().Value(func(val []byte) error {
+				return proto.Unmarshal(val, fileInfo)
+			})
+			if err != nil {
+				return err
+			}
+			name, err := identity(fileInfo.GetPath())
+			if err != nil {
+				log.Warn().Err(err).Msgf("Skipping file: %s", fileInfo.GetName())
+				continue
+			}
+			if name == fileInfo.GetName() {
+				continue
+			}
+			renames[fileInfo.GetName()] = name
+			fileInfo.Name = name
+			fileInfos = append(fileInfos, fileInfo)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read file index: %w", err)
+	}
+	if len(renames) == 0 {
+		log.Info().Msg("No files to migrate")
+		return nil
+	}
+	log.Info().Msgf("Migrating %d files", len(renames))
+
+	wb := db.FileIndex.NewWriteBatch()
+	for _, fileInfo := range fileInfos {
+		key, value, err := keyvalue.MarshalFileInfo(fileInfo, "")
+		if err != nil {
+			wb.Cancel()
+			return err
+		}
+		if err := wb.Set(key, value); err != nil {
+			wb.Cancel()
+			return err
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("failed to update file index: %w", err)
+	}
+
+	n, err := rename(ctx, db.IdIndex, renames, keyvalue.RenameId)
+	if err != nil {
+		return fmt.Errorf("failed to migrate id index: %w", err)
+	}
+	log.Info().Msgf("Migrated %d storage refs in id index", n)
+
+	n, err = rename(ctx, db.CdxIndex, renames, keyvalue.RenameCdx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate cdx index: %w", err)
+	}
+	log.Info().Msgf("Migrated %d storage refs in cdx index", n)
+
+	wb = db.FileIndex.NewWriteBatch()
+	for oldName := range renames {
+		if err := wb.Delete(keyvalue.Key(oldName)); err != nil {
+			wb.Cancel()
+			return err
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("failed to delete old file index entries: %w", err)
+	}
+	return nil
+}
+
+// rename rewrites the values of bdb with renamed storage refs and returns the number of values rewritten.
+func rename(ctx context.Context, bdb *badger.DB, renames map[string]string, fn func([]byte, map[string]string) ([]byte, bool, error)) (int, error) {
+	count := 0
+	wb := bdb.NewWriteBatch()
+	err := bdb.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := iter.Item()
+			var value []byte
+			var ok bool
+			err := item.Value(func(val []byte) (err error) {
+				value, ok, err = fn(val, renames)
+				return
+			})
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to migrate: %s", item.Key())
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if err := wb.Set(item.KeyCopy(nil), value); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		wb.Cancel()
+		return count, err
+	}
+	return count, wb.Flush()
+}
@@ -30,6 +30,7 @@ func defaultDbOptions() *Options {
 		BatchMaxWait: 5 * time.Second,
 		GcInterval:   15 * time.Second,
 		Path:         ".",
+		FileIdentity: index.BaseName,
 	}
 }
 
@@ -43,6 +44,7 @@ type Options struct {
 	Database     string
 	Index        index.Indexer
 	Silent       bool
+	FileIdentity index.FileIdentity
 }
 
 type Option func(opts *Options)
@@ -99,6 +101,13 @@ func WithIndexer(indexer index.Indexer) Option {
 	}
 }
 
+// WithFileIdentity sets how files are identified in the file index.
+func WithFileIdentity(identity index.FileIdentity) Option {
+	return func(opts *Options) {
+		opts.FileIdentity = identity
+	}
+}
+
 func WithoutBadgerLogging() Option {
 	return func(opts *Options) {
 		opts.Silent = true
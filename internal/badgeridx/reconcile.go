@@ -0,0 +1,177 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgeridx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reconcile compares the file index to the filesystem.
+//
+// Files that no longer exist at their indexed path are looked for with locate.
+// The path of a file that is found is updated, and if its identity has changed
+// the storage refs of its records are rewritten. The id and cdx entries of a
+// file that is not found are removed along with its file info.
+func (db *DB) Reconcile(ctx context.Context, locate index.FileLocator, dryRun bool) error {
+	var missing []*schema.FileInfo
+	indexed := make(map[string]string)
+
+	err := db.FileIndex.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			fileInfo := new(schema.FileInfo)
+			err := iter.Item().Value(func(val []byte) error {
+				return proto.Unmarshal(val, fileInfo)
+			})
+			if err != nil {
+				return err
+			}
+			indexed[fileInfo.GetName()] = fileInfo.GetPath()
+			_, err = os.Stat(fileInfo.GetPath())
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Warn().Err(err).Msgf("Skipping file: %s", fileInfo.GetName())
+				continue
+			}
+			missing = append(missing, fileInfo)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	located, err := keyvalue.LocateFiles(missing, indexed, locate, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	for _, fileInfo := range located.Moved {
+		if err := db.putFileInfo(fileInfo); err != nil {
+			return fmt.Errorf("failed to update file info: %s: %w", fileInfo.GetName(), err)
+		}
+	}
+	if len(located.Renames) > 0 {
+		n, err := rename(ctx, db.IdIndex, located.Renames, keyvalue.RenameId)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite id index: %w", err)
+		}
+		log.Info().Msgf("Rewrote %d storage refs in id index", n)
+
+		n, err = rename(ctx, db.CdxIndex, located.Renames, keyvalue.RenameCdx)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite cdx index: %w", err)
+		}
+		log.Info().Msgf("Rewrote %d storage refs in cdx index", n)
+
+		// old file infos are removed last so that an interrupted rewrite is resumed on the next run
+		wb := db.FileIndex.NewWriteBatch()
+		for name := range located.Renames {
+			if err := wb.Delete(keyvalue.Key(name)); err != nil {
+				wb.Cancel()
+				return err
+			}
+		}
+		if err := wb.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(located.Vanished) == 0 {
+		return nil
+	}
+
+	n, err := purge(ctx, db.IdIndex, located.Vanished, keyvalue.IdFilename)
+	if err != nil {
+		return fmt.Errorf("failed to purge id index: %w", err)
+	}
+	log.Info().Msgf("Removed %d entries from id index", n)
+
+	n, err = purge(ctx, db.CdxIndex, located.Vanished, keyvalue.CdxFilename)
+	if err != nil {
+		return fmt.Errorf("failed to purge cdx index: %w", err)
+	}
+	log.Info().Msgf("Removed %d entries from cdx index", n)
+
+	// file infos are removed last so that an interrupted purge is resumed on the next run
+	wb := db.FileIndex.NewWriteBatch()
+	for name := range located.Vanished {
+		if err := wb.Delete(keyvalue.Key(name)); err != nil {
+			wb.Cancel()
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// purge deletes the entries of bdb that refer to one of the files and returns the number of entries deleted.
+func purge(ctx context.Context, bdb *badger.DB, files map[string]bool, filename func([]byte) (string, error)) (int, error) {
+	count := 0
+	wb := bdb.NewWriteBatch()
+	err := bdb.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := iter.Item()
+			var name string
+			err := item.Value(func(val []byte) (err error) {
+				name, err = filename(val)
+				return
+			})
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed to get filename: %s", item.Key())
+				continue
+			}
+			if !files[name] {
+				continue
+			}
+			if err := wb.Delete(item.KeyCopy(nil)); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		wb.Cancel()
+		return count, err
+	}
+	return count, wb.Flush()
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package badgeridx
+
+import (
+	"context"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/surt"
+)
+
+// ListHosts lists the distinct hosts matching req.
+func (db *DB) ListHosts(ctx context.Context, req index.Request, results chan<- keyvalue.CdxResponse) error {
+	return db.listDistinct(ctx, req, keyvalue.CdxKey.NextHost, results)
+}
+
+// ListUrls lists the distinct urls matching req.
+func (db *DB) ListUrls(ctx context.Context, req index.Request, results chan<- keyvalue.CdxResponse) error {
+	return db.listDistinct(ctx, req, keyvalue.CdxKey.NextUrl, results)
+}
+
+// listDistinct sends the first key matching req and then skips to the key returned by next.
+//
+// Only keys are read unless the request has a filter.
+func (db *DB) listDistinct(ctx context.Context, req index.Request, next func(keyvalue.CdxKey) []byte, results chan<- keyvalue.CdxResponse) error {
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
+	prefix := keyvalue.SearchKey(req)
+	dateRange := req.DateRange()
+	filter := req.Filter()
+	keysOnly := index.IsEmptyFilter(filter)
+	budget := req.Budget()
+	matchType := req.MatchType()
+
+	go func() {
+		defer close(results)
+
+		_ = db.CdxIndex.View(func(txn *badger.Txn) error {
+			count := 0
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			opts.Prefix = prefix
+
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			it.Seek(prefix)
+			for it.ValidForPrefix(prefix) {
+				if budget != nil {
+					if err := budget.ScanKey(); err != nil {
+						results <- keyvalue.CdxResponse{Error: err}
+						return nil
+					}
+				}
+				key := keyvalue.CdxKey(it.Item().KeyCopy(nil))
+				if !dateRange.Contains(key.Unix()) {
+					it.Next()
+					continue
+				}
+				if matchType == index.MatchTypeVerbatim && key.PortSchemeUserInfo() != portSchemeUserInfo {
+					it.Next()
+					continue
+				}
+				cdxResponse := keyvalue.CdxResponse{Key: key}
+				if !keysOnly {
+					if budget != nil {
+						if err := budget.DecodeValue(); err != nil {
+							results <- keyvalue.CdxResponse{Error: err}
+							return nil
+						}
+					}
+					cdx, err := cdxFromItem(it.Item())
+					if err != nil {
+						cdxResponse = keyvalue.CdxResponse{Error: err}
+					} else if !filter.Eval(cdx) {
+						it.Next()
+						continue
+					} else {
+						cdxResponse.Value = cdx
+					}
+				}
+				select {
+				case <-ctx.Done():
+					results <- keyvalue.CdxResponse{Error: ctx.Err()}
+					return nil
+				case results <- cdxResponse:
+					if cdxResponse.GetError() == nil {
+						count++
+					}
+				}
+				if req.Limit() > 0 && count >= req.Limit() {
+					break
+				}
+				// skip the remaining keys of the host or url
+				it.Seek(next(key))
+			}
+			return nil
+		})
+	}()
+	return nil
+}
@@ -120,3 +120,13 @@ type DebugRequest struct {
 type DebugAPI interface {
 	Debug(context.Context, DebugRequest, chan<- CdxResponse) error
 }
+
+// UrlAPI lists the distinct hosts and urls of the cdx index.
+//
+// The response of each host or url is the key of its first capture matching the request.
+type UrlAPI interface {
+	// ListHosts lists the distinct hosts matching the request.
+	ListHosts(context.Context, index.Request, chan<- CdxResponse) error
+	// ListUrls lists the distinct urls, i.e. host and path, matching the request.
+	ListUrls(context.Context, index.Request, chan<- CdxResponse) error
+}
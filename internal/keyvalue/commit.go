@@ -0,0 +1,124 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyvalue
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+)
+
+type fileCommit struct {
+	fileInfo *schema.FileInfo
+	ready    bool
+	failed   int
+}
+
+// FileCommits keeps track of files being indexed so that a file is only
+// marked as indexed when every batch holding its records has been written.
+//
+// The protocol is as follows:
+//  1. Begin is called before the records of a file are written.
+//  2. Fail is called for every record of the file that could not be written.
+//  3. Commit is called when all records of the file have been scheduled for writing.
+//  4. The batch writer takes the ready commits with Ready before collecting
+//     the batch, writes the batch and then calls Done for each commit.
+//
+// Because every record of a file is scheduled before Commit is called, the
+// batch collected after Ready holds the remaining records of the ready files.
+type FileCommits struct {
+	mu    sync.Mutex
+	files map[string]*fileCommit
+}
+
+func NewFileCommits() *FileCommits {
+	return &FileCommits{
+		files: make(map[string]*fileCommit),
+	}
+}
+
+// Begin starts tracking the file at path.
+// It returns an error wrapping index.AlreadyIndexedError if the file is already
+// being indexed, so that a file is never tracked by two runs at once.
+func (fc *FileCommits) Begin(path string, fileInfo *schema.FileInfo) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if _, ok := fc.files[path]; ok {
+		return fmt.Errorf("%w: indexing in progress", index.AlreadyIndexedError)
+	}
+	fc.files[path] = &fileCommit{fileInfo: fileInfo}
+	return nil
+}
+
+// Fail records that a record of the file at path could not be written.
+func (fc *FileCommits) Fail(path string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if f, ok := fc.files[path]; ok {
+		f.failed++
+	}
+}
+
+// Commit marks the file at path as ready to be committed.
+func (fc *FileCommits) Commit(path string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	f, ok := fc.files[path]
+	if !ok {
+		return fmt.Errorf("no indexing in progress: %s", path)
+	}
+	f.ready = true
+	return nil
+}
+
+// Abort stops tracking the file at path without committing it.
+func (fc *FileCommits) Abort(path string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	delete(fc.files, path)
+}
+
+// Ready returns the paths of the files that are ready to be committed.
+func (fc *FileCommits) Ready() []string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	var paths []string
+	for path, f := range fc.files {
+		if f.ready {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Done stops tracking the file at path and returns its file info, or an error
+// if any of its records failed to be written.
+func (fc *FileCommits) Done(path string) (*schema.FileInfo, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	f, ok := fc.files[path]
+	if !ok {
+		return nil, fmt.Errorf("no indexing in progress: %s", path)
+	}
+	delete(fc.files, path)
+	if f.failed > 0 {
+		return nil, fmt.Errorf("failed to write %d records of %s", f.failed, path)
+	}
+	return f.fileInfo, nil
+}
@@ -0,0 +1,61 @@
+package keyvalue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+)
+
+func TestFileCommits(t *testing.T) {
+	fc := NewFileCommits()
+
+	for _, path := range []string{"a", "b", "c"} {
+		if err := fc.Begin(path, &schema.FileInfo{Name: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fc.Begin("a", &schema.FileInfo{Name: "a"}); !errors.Is(err, index.AlreadyIndexedError) {
+		t.Errorf("expected already indexed error when beginning a file being indexed, got %v", err)
+	}
+
+	if ready := fc.Ready(); len(ready) != 0 {
+		t.Errorf("expected no files to be ready, got %v", ready)
+	}
+
+	if err := fc.Commit("a"); err != nil {
+		t.Fatal(err)
+	}
+	fc.Fail("b")
+	if err := fc.Commit("b"); err != nil {
+		t.Fatal(err)
+	}
+	fc.Abort("c")
+	if err := fc.Commit("c"); err == nil {
+		t.Error("expected error when committing aborted file")
+	}
+
+	ready := fc.Ready()
+	if len(ready) != 2 {
+		t.Fatalf("expected 2 files to be ready, got %v", ready)
+	}
+	for _, path := range ready {
+		fileInfo, err := fc.Done(path)
+		switch path {
+		case "a":
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if fileInfo.GetName() != "a" {
+				t.Errorf("got %s, want %s", fileInfo.GetName(), "a")
+			}
+		case "b":
+			if err == nil {
+				t.Error("expected error for file with failed records")
+			}
+		}
+	}
+	if ready := fc.Ready(); len(ready) != 0 {
+		t.Errorf("expected no files to be ready, got %v", ready)
+	}
+}
@@ -2,17 +2,19 @@ package keyvalue
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	"github.com/nlnwa/gowarcserver/index"
 	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
 	"github.com/nlnwa/gowarcserver/timestamp"
 	"google.golang.org/protobuf/proto"
 )
 
 func ClosestWithPrefix(req index.Request, prefix string) ([]byte, []byte) {
 	ssurt := scope(req.Ssurt(), index.MatchTypeExact)
-	host, _, path := SplitSSURT(ssurt)
+	host, _, path := surt.SplitSSURT(ssurt)
 	closest := req.Closest()
 	p := prefix + host + path
 	return []byte(p), []byte(p + closest)
@@ -23,7 +25,7 @@ func Closest(req index.Request) ([]byte, []byte) {
 }
 
 func SearchKeyWithPrefix(req index.Request, prefix string) []byte {
-	host, _, path := SplitSSURT(scope(req.Ssurt(), req.MatchType()))
+	host, _, path := surt.SplitSSURT(scope(req.Ssurt(), req.MatchType()))
 	return []byte(prefix + host + path)
 }
 
@@ -39,12 +41,16 @@ func Key(key string) []byte {
 	return KeyWithPrefix(key, "")
 }
 
+// CdxKeyOf returns the key of cdx in the cdx index.
+func CdxKeyOf(cdx *schema.Cdx) CdxKey {
+	ts := timestamp.TimeTo14(cdx.GetSts().AsTime())
+	host, schemeAndUserinfo, path := surt.SplitSSURT(cdx.GetSsu())
+	return CdxKey(host + path + " " + ts + " " + schemeAndUserinfo + " " + cdx.GetSrt())
+}
+
 // MarshalCdxWithPrefix takes a record and returns a key-value pair for the cdx index.
 func MarshalCdxWithPrefix(r index.Record, prefix string) (key []byte, value []byte, err error) {
-	ts := timestamp.TimeTo14(r.GetSts().AsTime())
-	ssurt := r.GetSsu()
-	host, schemeAndUserinfo, path := SplitSSURT(ssurt)
-	key = []byte(prefix + host + path + " " + ts + " " + schemeAndUserinfo + " " + r.GetSrt())
+	key = append([]byte(prefix), CdxKeyOf(r.Cdx)...)
 	value, err = r.Marshal()
 	return
 }
@@ -89,9 +95,7 @@ type CdxKey []byte
 // byte constants used in the key
 var (
 	spaceCharacter = []byte{32} // ' '
-	colonCharacter = []byte{58} // ':'
 	slashCharacter = []byte{47} // '/'
-	atCharacter    = []byte{64} // '@'
 )
 
 func (ck CdxKey) domainPath() []byte {
@@ -120,24 +124,6 @@ func (ck CdxKey) responseType() []byte {
 	return parts[3]
 }
 
-func (ck CdxKey) port() []byte {
-	return bytes.Split(ck.portSchemeUserInfo(), colonCharacter)[0]
-}
-
-func (ck CdxKey) schemeUserInfo() []byte {
-	portSchemeUserInfo := ck.portSchemeUserInfo()
-	if portSchemeUserInfo == nil {
-		return nil
-	}
-
-	portAndSchemeAndUserInfo := bytes.SplitN(ck.portSchemeUserInfo(), colonCharacter, 2)
-	if len(portAndSchemeAndUserInfo) < 2 {
-		return nil
-	}
-	schemeUserInfo := portAndSchemeAndUserInfo[1]
-	return bytes.TrimRight(schemeUserInfo, ":")
-}
-
 func (ck CdxKey) String() string {
 	return string(ck)
 }
@@ -161,13 +147,68 @@ func (ck CdxKey) Time() time.Time {
 	return t
 }
 
+// Timestamp returns the time part of the key as a 14 digit timestamp.
+func (ck CdxKey) Timestamp() string {
+	return string(ck.timestamp())
+}
+
+// NextHost returns the smallest key greater than all keys with the same host as ck.
+//
+// Keys of a host are followed by either '/' (path) or ' ' (no path), so seeking
+// past host + "/" skips every key of the host.
+func (ck CdxKey) NextHost() []byte {
+	domain := bytes.Split(ck.domainPath(), slashCharacter)[0]
+	return append(append([]byte{}, domain...), slashCharacter[0]+1)
+}
+
+// NextUrl returns the smallest key greater than all keys with the same host and path as ck.
+func (ck CdxKey) NextUrl() []byte {
+	return append(append([]byte{}, ck.domainPath()...), spaceCharacter[0]+1)
+}
+
+// Host returns the host of the key, e.g. www.example.com for the surt host com,example,www,.
+func (ck CdxKey) Host() string {
+	domain := ck.Domain()
+	// ip addresses are not reversed
+	if !strings.Contains(domain, ",") {
+		return domain
+	}
+	return deSurtDomain(domain)
+}
+
+// Url returns the url of the key, e.g. http://www.example.com:8080/path.
+func (ck CdxKey) Url() string {
+	var sb strings.Builder
+	sb.WriteString(ck.Scheme())
+	path := ck.Path()
+	// uris without a path are not hierarchical, e.g. dns:www.example.com
+	if path == "" {
+		sb.WriteByte(':')
+		sb.WriteString(ck.Host())
+		return sb.String()
+	}
+	sb.WriteString("://")
+	if userInfo := ck.UserInfo(); userInfo != "" {
+		sb.WriteString(userInfo)
+		sb.WriteByte('@')
+	}
+	sb.WriteString(ck.Host())
+	if port := ck.Port(); port != "" {
+		sb.WriteByte(':')
+		sb.WriteString(port)
+	}
+	sb.WriteString(path)
+	return sb.String()
+}
+
 // Unix returns the time part of the key as unix time.
 func (ck CdxKey) Unix() int64 {
 	return ck.Time().Unix()
 }
 
 func (ck CdxKey) Port() string {
-	return string(ck.port())
+	port, _, _ := surt.SplitPortSchemeUserInfo(ck.PortSchemeUserInfo())
+	return port
 }
 
 func (ck CdxKey) PortSchemeUserInfo() string {
@@ -179,25 +220,13 @@ func (ck CdxKey) PortSchemeUserInfo() string {
 }
 
 func (ck CdxKey) Scheme() string {
-	schemeUserInfo := ck.schemeUserInfo()
-	if schemeUserInfo == nil {
-		return ""
-	}
-	scheme := bytes.Split(schemeUserInfo, atCharacter)[0]
-	return string(scheme)
+	_, scheme, _ := surt.SplitPortSchemeUserInfo(ck.PortSchemeUserInfo())
+	return scheme
 }
 
 func (ck CdxKey) UserInfo() string {
-	schemeUserInfo := ck.schemeUserInfo()
-	if schemeUserInfo == nil {
-		return ""
-	}
-	schemeAndUserInfo := bytes.Split(schemeUserInfo, atCharacter)
-	if len(schemeAndUserInfo) < 2 {
-		return ""
-	}
-	userInfo := schemeAndUserInfo[1]
-	return string(userInfo)
+	_, _, userInfo := surt.SplitPortSchemeUserInfo(ck.PortSchemeUserInfo())
+	return userInfo
 }
 
 func (ck CdxKey) ResponseType() string {
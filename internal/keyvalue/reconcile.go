@@ -0,0 +1,78 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyvalue
+
+import (
+	"fmt"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// Located is the result of looking for files missing from their indexed path.
+type Located struct {
+	// Moved are the file infos of the files found, updated with their new path and identity.
+	Moved []*schema.FileInfo
+	// Renames maps the old identity of moved files to their new identity, if it changed.
+	Renames map[string]string
+	// Vanished are the identities of the files not found.
+	Vanished map[string]bool
+}
+
+// LocateFiles looks for the missing files with locate. Indexed maps the identities
+// of the indexed files to their paths.
+//
+// A file found under a new identity that is already in use, either by an indexed
+// file at another path or by another moved file, is considered vanished so that
+// its records are not mixed up with the records of the other file. An indexed
+// file at the path the file was found at is the file itself, either indexed again
+// or moved by an interrupted reconcile, so the rename is resumed.
+func LocateFiles(missing []*schema.FileInfo, indexed map[string]string, locate index.FileLocator, dryRun bool) (*Located, error) {
+	located := &Located{
+		Renames:  make(map[string]string),
+		Vanished: make(map[string]bool),
+	}
+	// moved are the new identities of the files moved by this reconcile
+	moved := make(map[string]bool)
+	for _, fileInfo := range missing {
+		path, name, err := locate(fileInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate file %s: %w", fileInfo.GetName(), err)
+		}
+		if path == "" {
+			log.Info().Bool("dryRun", dryRun).Msgf("File has vanished: %s", fileInfo.GetPath())
+			located.Vanished[fileInfo.GetName()] = true
+			continue
+		}
+		if name != fileInfo.GetName() {
+			if indexedPath, ok := indexed[name]; moved[name] || ok && indexedPath != path {
+				log.Info().Bool("dryRun", dryRun).Msgf("File has moved to an already indexed file: %s -> %s", fileInfo.GetPath(), path)
+				located.Vanished[fileInfo.GetName()] = true
+				continue
+			}
+			moved[name] = true
+			located.Renames[fileInfo.GetName()] = name
+		}
+		log.Info().Bool("dryRun", dryRun).Msgf("File has moved: %s -> %s", fileInfo.GetPath(), path)
+		fileInfo.Path = path
+		fileInfo.Name = name
+		located.Moved = append(located.Moved, fileInfo)
+	}
+	log.Info().Bool("dryRun", dryRun).Msgf("Found %d moved and %d vanished files", len(located.Moved), len(located.Vanished))
+	return located, nil
+}
@@ -20,31 +20,6 @@ import (
 	"strings"
 )
 
-func SplitSSURT(ssurt string) (surtHost string, portSchemeUserInfo string, path string) {
-	i := strings.Index(ssurt, "//")
-	if i == -1 {
-		return ssurt, "", ""
-	}
-	surtHost = ssurt[:i]
-
-	if len(ssurt) < i+2 {
-		return surtHost, "", ""
-	}
-	if len(ssurt) == i+2 {
-		return surtHost, "", "/"
-	}
-
-	j := strings.Index(ssurt[i+2:], "/")
-	if j == -1 {
-		return surtHost, ssurt[i+2:], ""
-	}
-	portSchemeUserInfo = ssurt[i+2 : i+2+j]
-
-	path = ssurt[i+2+j:]
-
-	return
-}
-
 func deSurtDomain(domain string) string {
 	domain = strings.Trim(domain, ",")
 	t := strings.Split(domain, ",")
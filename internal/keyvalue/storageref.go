@@ -0,0 +1,79 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyvalue
+
+import (
+	"strconv"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
+	"google.golang.org/protobuf/proto"
+)
+
+const storageRefScheme = "warcfile:"
+
+// RenameStorageRef replaces the filename of storageRef with its new name in renames.
+// It returns false if the filename is not renamed.
+func RenameStorageRef(storageRef string, renames map[string]string) (string, bool) {
+	filename, offset, err := loader.ParseStorageRef(storageRef)
+	if err != nil {
+		return storageRef, false
+	}
+	name, ok := renames[filename]
+	if !ok {
+		return storageRef, false
+	}
+	return storageRefScheme + name + "#" + strconv.FormatInt(offset, 10), true
+}
+
+// IdFilename returns the filename of the storage ref of a value in the id index.
+func IdFilename(value []byte) (string, error) {
+	filename, _, err := loader.ParseStorageRef(string(value))
+	return filename, err
+}
+
+// CdxFilename returns the filename of the storage ref of a value in the cdx index.
+func CdxFilename(value []byte) (string, error) {
+	cdx := new(schema.Cdx)
+	if err := proto.Unmarshal(value, cdx); err != nil {
+		return "", err
+	}
+	filename, _, err := loader.ParseStorageRef(cdx.GetRef())
+	return filename, err
+}
+
+// RenameId renames the storage ref of a value in the id index.
+func RenameId(value []byte, renames map[string]string) ([]byte, bool, error) {
+	ref, ok := RenameStorageRef(string(value), renames)
+	return []byte(ref), ok, nil
+}
+
+// RenameCdx renames the storage ref of a value in the cdx index.
+func RenameCdx(value []byte, renames map[string]string) ([]byte, bool, error) {
+	cdx := new(schema.Cdx)
+	if err := proto.Unmarshal(value, cdx); err != nil {
+		return nil, false, err
+	}
+	ref, ok := RenameStorageRef(cdx.GetRef(), renames)
+	if !ok {
+		return value, false, nil
+	}
+	cdx.Ref = ref
+	value, err := index.Record{Cdx: cdx}.Marshal()
+	return value, true, err
+}
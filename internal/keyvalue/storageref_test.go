@@ -0,0 +1,45 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package keyvalue
+
+import "testing"
+
+func TestRenameStorageRef(t *testing.T) {
+	renames := map[string]string{
+		"file.warc.gz":   "a/file.warc.gz",
+		"file#1.warc.gz": "b/file#1.warc.gz",
+	}
+	tests := []struct {
+		ref  string
+		want string
+		ok   bool
+	}{
+		{"warcfile:file.warc.gz#123", "warcfile:a/file.warc.gz#123", true},
+		{"warcfile:file#1.warc.gz#0", "warcfile:b/file#1.warc.gz#0", true},
+		{"warcfile:other.warc.gz#123", "warcfile:other.warc.gz#123", false},
+		{"warcfile:file.warc.gz", "warcfile:file.warc.gz", false},
+		{"other:file.warc.gz#123", "other:file.warc.gz#123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := RenameStorageRef(tt.ref, renames)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got (%s, %v), want (%s, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
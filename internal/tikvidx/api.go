@@ -17,6 +17,7 @@
 package tikvidx
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -25,6 +26,7 @@ import (
 	"github.com/nlnwa/gowarcserver/internal/keyvalue"
 	"github.com/nlnwa/gowarcserver/loader"
 	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -42,6 +44,21 @@ var _ index.FileAPI = (*DB)(nil)
 // Assert DB implements the index.IdAPI interface.
 var _ index.IdAPI = (*DB)(nil)
 
+// Assert that DB implements index.Committer
+var _ index.Committer = (*DB)(nil)
+
+// Assert that DB implements index.FileIdentityMigrator
+var _ index.FileIdentityMigrator = (*DB)(nil)
+
+// Assert that DB implements index.HistogramAPI
+var _ index.HistogramAPI = (*DB)(nil)
+
+// Assert DB implements the keyvalue.UrlAPI interface.
+var _ keyvalue.UrlAPI = (*DB)(nil)
+
+// Assert that DB implements index.Reconciler
+var _ index.Reconciler = (*DB)(nil)
+
 // Assert that DB implements index.ReportGenerator
 var _ index.ReportGenerator = (*DB)(nil)
 
@@ -63,7 +80,7 @@ type iterator interface {
 func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, res chan<- keyvalue.CdxResponse) error {
 	key := keyvalue.KeyWithPrefix(req.Key, cdxPrefix)
 
-	it, err := newIter(ctx, key, db.client, req, cdxPrefix)
+	it, err := newIter(ctx, key, nil, db.client, req, cdxPrefix)
 	if err != nil {
 		return err
 	}
@@ -71,6 +88,7 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, res chan<- k
 		close(res)
 		return nil
 	}
+	budget := req.Budget()
 	go func() {
 		defer close(res)
 		defer it.Close()
@@ -78,9 +96,19 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, res chan<- k
 		for it.Valid() {
 			cdxResponse := func() *keyvalue.CdxResponse {
 				cdxKey := keyvalue.CdxKey(it.Key())
+				if budget != nil {
+					if err := budget.ScanKey(); err != nil {
+						return &keyvalue.CdxResponse{Error: err}
+					}
+				}
 				if !req.DateRange().Contains(cdxKey.Unix()) {
 					return nil
 				}
+				if budget != nil {
+					if err := budget.DecodeValue(); err != nil {
+						return &keyvalue.CdxResponse{Error: err}
+					}
+				}
 				cdx := new(schema.Cdx)
 				if err := proto.Unmarshal(it.Value(), cdx); err != nil {
 					return &keyvalue.CdxResponse{Error: err}
@@ -96,6 +124,10 @@ func (db *DB) Debug(ctx context.Context, req keyvalue.DebugRequest, res chan<- k
 				return
 			case res <- *cdxResponse:
 			}
+			// stop iteration if the budget is exceeded
+			if index.IsBudgetExceeded(cdxResponse.GetError()) {
+				return
+			}
 			if err = it.Next(); err != nil {
 				res <- keyvalue.CdxResponse{Error: err}
 				return
@@ -112,7 +144,14 @@ func (db *DB) Search(ctx context.Context, req index.Request, res chan<- index.Cd
 		it, err = newClosestIter(ctx, db.client, req, cdxPrefix)
 	} else {
 		key := keyvalue.SearchKeyWithPrefix(req, cdxPrefix)
-		it, err = newIter(ctx, key, db.client, req, cdxPrefix)
+		var resumeKey []byte
+		if req.ResumeKey() != "" {
+			resumeKey = keyvalue.KeyWithPrefix(req.ResumeKey(), cdxPrefix)
+			if !bytes.HasPrefix(resumeKey, key) {
+				return fmt.Errorf("%w: %s", index.InvalidResumeKeyError, req.ResumeKey())
+			}
+		}
+		it, err = newIter(ctx, key, resumeKey, db.client, req, cdxPrefix)
 	}
 	if err != nil {
 		return err
@@ -122,7 +161,21 @@ func (db *DB) Search(ctx context.Context, req index.Request, res chan<- index.Cd
 		return nil
 	}
 	matchType := req.MatchType()
-	_, portSchemeUserInfo, _ := keyvalue.SplitSSURT(req.Ssurt())
+	collapser := req.Collapse()
+	// collapse results equal to the last result of the previous search
+	if collapser != nil && req.ResumeKey() != "" {
+		value, err := db.client.Get(ctx, keyvalue.KeyWithPrefix(req.ResumeKey(), cdxPrefix))
+		if err != nil {
+			it.Close()
+			return err
+		}
+		cdx := new(schema.Cdx)
+		if value != nil && proto.Unmarshal(value, cdx) == nil {
+			collapser.Collapse(cdx)
+		}
+	}
+	budget := req.Budget()
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
 
 	go func() {
 		defer close(res)
@@ -133,6 +186,11 @@ func (db *DB) Search(ctx context.Context, req index.Request, res chan<- index.Cd
 		for it.Valid() {
 			cdxResponse := func() *keyvalue.CdxResponse {
 				cdxKey := keyvalue.CdxKey(it.Key())
+				if budget != nil {
+					if err := budget.ScanKey(); err != nil {
+						return &keyvalue.CdxResponse{Error: err}
+					}
+				}
 				if !req.DateRange().Contains(cdxKey.Unix()) {
 					return nil
 				}
@@ -141,10 +199,15 @@ func (db *DB) Search(ctx context.Context, req index.Request, res chan<- index.Cd
 						return nil
 					}
 				}
+				if budget != nil {
+					if err := budget.DecodeValue(); err != nil {
+						return &keyvalue.CdxResponse{Error: err}
+					}
+				}
 				cdx := new(schema.Cdx)
 				if err := proto.Unmarshal(it.Value(), cdx); err != nil {
 					return &keyvalue.CdxResponse{Error: err}
-				} else if req.Filter().Eval(cdx) {
+				} else if req.Filter().Eval(cdx) && (collapser == nil || !collapser.Collapse(cdx)) {
 					return &keyvalue.CdxResponse{
 						Key:   cdxKey,
 						Value: cdx,
@@ -168,6 +231,10 @@ func (db *DB) Search(ctx context.Context, req index.Request, res chan<- index.Cd
 					count++
 				}
 			}
+			// stop iteration if the budget is exceeded
+			if index.IsBudgetExceeded(cdxResponse.GetError()) {
+				break
+			}
 			if req.Limit() > 0 && count >= req.Limit() {
 				break
 			}
@@ -186,7 +253,7 @@ func (db *DB) GetFileInfo(_ context.Context, filename string) (*schema.FileInfo,
 
 func (db *DB) ListFileInfo(ctx context.Context, req index.Request, res chan<- index.FileInfoResponse) error {
 	key := []byte(filePrefix)
-	it, err := newIter(ctx, key, db.client, req, filePrefix)
+	it, err := newIter(ctx, key, nil, db.client, req, filePrefix)
 	if err != nil {
 		return err
 	}
@@ -238,7 +305,7 @@ func (db *DB) GetStorageRef(ctx context.Context, id string) (string, error) {
 
 func (db *DB) ListStorageRef(ctx context.Context, req index.Request, res chan<- index.IdResponse) error {
 	key := []byte(idPrefix)
-	it, err := newIter(ctx, key, db.client, req, idPrefix)
+	it, err := newIter(ctx, key, nil, db.client, req, idPrefix)
 	if err != nil {
 		return err
 	}
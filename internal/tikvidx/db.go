@@ -44,11 +44,14 @@ var (
 const delimiter = "_"
 
 type DB struct {
-	client *rawkv.Client
-	batch  chan index.Record
-	done   chan struct{}
-	wg     sync.WaitGroup
-	tasks  map[string]context.CancelFunc
+	client   *rawkv.Client
+	batch    chan index.Record
+	commits  *keyvalue.FileCommits
+	flushMu  sync.Mutex
+	identity index.FileIdentity
+	done     chan struct{}
+	wg       sync.WaitGroup
+	tasks    map[string]context.CancelFunc
 }
 
 func NewDB(options ...Option) (db *DB, err error) {
@@ -77,9 +80,11 @@ func NewDB(options ...Option) (db *DB, err error) {
 	done := make(chan struct{})
 
 	db = &DB{
-		client: client,
-		done:   done,
-		tasks:  make(map[string]context.CancelFunc),
+		client:   client,
+		commits:  keyvalue.NewFileCommits(),
+		identity: opts.FileIdentity,
+		done:     done,
+		tasks:    make(map[string]context.CancelFunc),
 	}
 
 	if opts.ReadOnly {
@@ -92,7 +97,7 @@ func NewDB(options ...Option) (db *DB, err error) {
 	db.wg.Add(1)
 	go func() {
 		defer db.wg.Done()
-		ticker := time.NewTimer(opts.BatchMaxWait)
+		ticker := time.NewTicker(opts.BatchMaxWait)
 		defer ticker.Stop()
 		for {
 			select {
@@ -118,7 +123,8 @@ func (db *DB) Close() {
 	_ = db.client.Close()
 }
 
-// addFile checks if file referenced by filePath is indexed or has changed and adds/updates the index accordingly.
+// addFile checks if file referenced by filePath is indexed or has changed and starts tracking the file
+// so that the file index can be updated when all of its records have been written.
 func (db *DB) addFile(filePath string) error {
 	stat, err := os.Stat(filePath)
 	if err != nil {
@@ -127,7 +133,10 @@ func (db *DB) addFile(filePath string) error {
 
 	fileSize := stat.Size()
 	fileLastModified := stat.ModTime()
-	fn := filepath.Base(filePath)
+	fn, err := db.identity(filePath)
+	if err != nil {
+		return err
+	}
 	if fileInfo, err := db.getFileInfo(fn); err == nil && fileInfo != nil {
 		if err := fileInfo.GetLastModified().CheckValid(); err != nil {
 			return err
@@ -138,28 +147,33 @@ func (db *DB) addFile(filePath string) error {
 		}
 	}
 
-	return db.updateFilePath(filePath)
+	fileInfo, err := newFileInfo(filePath, fn)
+	if err != nil {
+		return err
+	}
+	return db.commits.Begin(filePath, fileInfo)
 }
 
-func (db *DB) updateFilePath(filePath string) error {
+// newFileInfo returns the file info of the file referenced by filePath and identified by name.
+func newFileInfo(filePath string, name string) (*schema.FileInfo, error) {
 	var err error
 	fileInfo := new(schema.FileInfo)
 
 	fileInfo.Path, err = filepath.Abs(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fileInfo.Name = filepath.Base(fileInfo.Path)
+	fileInfo.Name = name
 	stat, err := os.Stat(fileInfo.Path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	fileInfo.Size = stat.Size()
 	fileInfo.LastModified = timestamppb.New(stat.ModTime())
 
-	return db.putFileInfo(fileInfo)
+	return fileInfo, nil
 }
 
 func (db *DB) putFileInfo(fi *schema.FileInfo) error {
@@ -202,7 +216,8 @@ func (db *DB) getFileInfo(fileName string) (*schema.FileInfo, error) {
 func (db *DB) write(rec index.Record) {
 	select {
 	case <-db.done:
-		// do nothing
+		// record is dropped so the file must be indexed again
+		db.commits.Fail(rec.File())
 	case db.batch <- rec:
 		// added record to batch
 	default:
@@ -215,9 +230,12 @@ func (db *DB) write(rec index.Record) {
 // see https://github.com/tikv/tikv/blob/a0e8a7a163302bc9a7be5fd5a903b6a156797eb8/src/storage/config.rs#L21
 const tikvMaxKeySize = 8 * 1024
 
-func (db *DB) collectBatch() ([][]byte, [][]byte) {
+// collectBatch returns the keys and values of all the records in the batch channel
+// and the paths of the files the records were read from.
+func (db *DB) collectBatch() ([][]byte, [][]byte, []string) {
 	var keys [][]byte
 	var values [][]byte
+	var files []string
 	for {
 		select {
 		case r := <-db.batch:
@@ -235,15 +253,39 @@ func (db *DB) collectBatch() ([][]byte, [][]byte) {
 			}
 			keys = append(keys, idKey, cdxKey)
 			values = append(values, idValue, cdxValue)
+			files = append(files, r.File())
 		default:
-			return keys, values
+			return keys, values, files
 		}
 	}
 }
 
 // FlushBatch collects all records in the batch channel and updates the id and cdx indices.
+// Files with all records written are then added to the file index.
 func (db *DB) FlushBatch() {
-	keys, values := db.collectBatch()
+	db.flushMu.Lock()
+	defer db.flushMu.Unlock()
+
+	// files must be collected before the batch to ensure all their records are in the batch
+	ready := db.commits.Ready()
+
+	db.writeBatch(db.collectBatch())
+
+	for _, path := range ready {
+		fileInfo, err := db.commits.Done(path)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Not marking file as indexed, it will be indexed again on next run: %s", path)
+			continue
+		}
+		// the file is left unmarked, and indexed again on next run, if its file info is not written
+		if err := db.putFileInfo(fileInfo); err != nil {
+			log.Error().Err(err).Msgf("Failed to update file index, it will be indexed again on next run: %s", path)
+		}
+	}
+}
+
+// writeBatch puts keys and values in the database.
+func (db *DB) writeBatch(keys [][]byte, values [][]byte, files []string) {
 	if len(keys) == 0 {
 		return
 	}
@@ -254,6 +296,9 @@ func (db *DB) FlushBatch() {
 	err := db.client.BatchPut(ctx, keys, values)
 	if err != nil {
 		log.Error().Err(err).Msgf("Batch put failed")
+		for _, file := range files {
+			db.commits.Fail(file)
+		}
 	}
 }
 
@@ -275,3 +320,13 @@ func (db *DB) Write(rec index.Record) error {
 func (db *DB) Index(path string) error {
 	return db.addFile(path)
 }
+
+// Commit marks the file at path as indexed when all of its records have been written.
+func (db *DB) Commit(path string) error {
+	return db.commits.Commit(path)
+}
+
+// Abort stops tracking the file at path without marking it as indexed.
+func (db *DB) Abort(path string) {
+	db.commits.Abort(path)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tikvidx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
+	"github.com/tikv/client-go/v2/rawkv"
+	"google.golang.org/protobuf/proto"
+)
+
+// Histogram counts the captures matching req by the first n digits of their timestamps.
+//
+// Only keys are scanned unless the request has a filter. If the scan fails or the
+// budget of the request is exceeded the captures counted so far are returned with the error.
+func (db *DB) Histogram(ctx context.Context, req index.Request, n int) (map[string]uint64, error) {
+	if n < 1 || n > 14 {
+		return nil, fmt.Errorf("invalid number of timestamp digits: %d", n)
+	}
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
+	key := keyvalue.SearchKeyWithPrefix(req, cdxPrefix)
+	endKey := append(append([]byte{}, key...), 0xff)
+	dateRange := req.DateRange()
+	filter := req.Filter()
+	keysOnly := index.IsEmptyFilter(filter)
+	budget := req.Budget()
+	matchType := req.MatchType()
+
+	var opts []rawkv.RawOption
+	if keysOnly {
+		opts = append(opts, rawkv.ScanKeyOnly())
+	}
+	scan := func(key []byte, endKey []byte) ([][]byte, [][]byte, error) {
+		return db.client.Scan(ctx, key, endKey, rawkv.MaxRawKVScanLimit, opts...)
+	}
+	result := make(chan maybeKV)
+	done := make(chan struct{})
+	defer close(done)
+
+	go repeatScan(scan, key, endKey, false, result, done)
+
+	histogram := make(map[string]uint64)
+	for kv := range result {
+		if kv.error != nil {
+			return histogram, kv.error
+		}
+		if budget != nil {
+			if err := budget.ScanKey(); err != nil {
+				return histogram, err
+			}
+		}
+		cdxKey := keyvalue.CdxKey(kv.k[len(cdxPrefix):])
+		if !dateRange.Contains(cdxKey.Unix()) {
+			continue
+		}
+		if matchType == index.MatchTypeVerbatim && cdxKey.PortSchemeUserInfo() != portSchemeUserInfo {
+			continue
+		}
+		if !keysOnly {
+			if budget != nil {
+				if err := budget.DecodeValue(); err != nil {
+					return histogram, err
+				}
+			}
+			cdx := new(schema.Cdx)
+			if err := proto.Unmarshal(kv.v, cdx); err != nil {
+				return nil, err
+			}
+			if !filter.Eval(cdx) {
+				continue
+			}
+		}
+		ts := cdxKey.Timestamp()
+		histogram[ts[:min(n, len(ts))]]++
+	}
+	return histogram, nil
+}
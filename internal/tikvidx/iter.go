@@ -32,9 +32,11 @@ type maybeKV struct {
 	error error
 }
 
-type scanner func([]byte, []byte) ([][]byte, [][]byte, error)
+// scanner scans from key towards endKey. Reverse scanners scan from key (exclusive) down to endKey.
+type scanner func(key []byte, endKey []byte) ([][]byte, [][]byte, error)
 
-func repeatScan(scan scanner, key, endKey []byte, result chan<- maybeKV, done <-chan struct{}) {
+// repeatScan scans from key towards endKey until there are no more keys and sends the results to result.
+func repeatScan(scan scanner, key, endKey []byte, reverse bool, result chan<- maybeKV, done <-chan struct{}) {
 	defer close(result)
 	for {
 		keys, values, err := scan(key, endKey)
@@ -61,7 +63,12 @@ func repeatScan(scan scanner, key, endKey []byte, result chan<- maybeKV, done <-
 		if len(keys) < rawkv.MaxRawKVScanLimit {
 			return
 		}
-		key = append(keys[len(keys)-1], 0x0)
+		if reverse {
+			// reverse scans exclude the start key
+			key = keys[len(keys)-1]
+		} else {
+			key = append(keys[len(keys)-1], 0x0)
+		}
 	}
 }
 
@@ -115,8 +122,8 @@ func newClosestIter(ctx context.Context, client *rawkv.Client, req index.Request
 	copy(backwardStartKey, startKey)
 
 	done := make(chan struct{})
-	go repeatScan(fScanner, forwardStartKey, forwardEndKey, forwardChannel, done)
-	go repeatScan(bScanner, backwardStartKey, backwardEndKey, backwardChannel, done)
+	go repeatScan(fScanner, forwardStartKey, forwardEndKey, false, forwardChannel, done)
+	go repeatScan(bScanner, backwardStartKey, backwardEndKey, true, backwardChannel, done)
 
 	iter := &closestIter{
 		cmp:      timestamp.CompareClosest(t.Unix()),
@@ -203,29 +210,42 @@ type iter struct {
 	prefix string
 }
 
-func newIter(ctx context.Context, key []byte, client *rawkv.Client, req index.Request, prefix string) (iterator, error) {
+// newIter returns an iterator over the keys starting with key.
+// If resumeKey is not nil the iteration continues after resumeKey.
+func newIter(ctx context.Context, key []byte, resumeKey []byte, client *rawkv.Client, req index.Request, prefix string) (iterator, error) {
 	limit := req.Limit()
 	if limit == 0 || limit > rawkv.MaxRawKVScanLimit {
 		limit = rawkv.MaxRawKVScanLimit
 	}
+	startKey := make([]byte, len(key))
+	copy(startKey, key)
 	endKey := make([]byte, len(key))
 	copy(endKey, key)
 	endKey = append(endKey, 0xff)
 
+	reverse := req.Sort() == index.SortDesc
+
 	var scan scanner
-	if req.Sort() == index.SortDesc {
+	if reverse {
 		scan = func(key []byte, endKey []byte) ([][]byte, [][]byte, error) {
-			return client.ReverseScan(ctx, endKey, key, limit)
+			return client.ReverseScan(ctx, key, endKey, limit)
 		}
+		if resumeKey != nil {
+			endKey = resumeKey
+		}
+		startKey, endKey = endKey, startKey
 	} else {
 		scan = func(key []byte, endKey []byte) ([][]byte, [][]byte, error) {
 			return client.Scan(ctx, key, endKey, limit)
 		}
+		if resumeKey != nil {
+			startKey = append(append([]byte{}, resumeKey...), 0x0)
+		}
 	}
 	result := make(chan maybeKV)
 	done := make(chan struct{})
 
-	go repeatScan(scan, key, endKey, result, done)
+	go repeatScan(scan, startKey, endKey, reverse, result, done)
 
 	is := &iter{
 		next:   result,
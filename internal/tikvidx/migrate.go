@@ -0,0 +1,164 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tikvidx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+	"github.com/tikv/client-go/v2/rawkv"
+	"google.golang.org/protobuf/proto"
+)
+
+// MigrateFileIdentity changes the identity of the files in the file index and
+// rewrites the storage refs of the id and cdx indices accordingly.
+//
+// The file infos are added under their new identity before the storage refs are
+// rewritten, and the old file infos are deleted last, so that every storage ref
+// can be resolved during the migration and an interrupted migration can be run again.
+func (db *DB) MigrateFileIdentity(ctx context.Context, identity index.FileIdentity) error {
+	renames := make(map[string]string)
+	var keys [][]byte
+	var values [][]byte
+
+	err := db.scanAll(ctx, filePrefix, func(_ []byte, v []byte) error {
+		fileInfo := new(schema.FileInfo)
+		if err := proto.Unmarshal(v, fileInfo); err != nil {
+			return err
+		}
+		name, err := identity(fileInfo.GetPath())
+		if err != nil {
+			log.Warn().Err(err).Msgf("Skipping file: %s", fileInfo.GetName())
+			return nil
+		}
+		if name == fileInfo.GetName() {
+			return nil
+		}
+		renames[fileInfo.GetName()] = name
+		fileInfo.Name = name
+		key, value, err := keyvalue.MarshalFileInfo(fileInfo, filePrefix)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read file index: %w", err)
+	}
+	if len(renames) == 0 {
+		log.Info().Msg("No files to migrate")
+		return nil
+	}
+	log.Info().Msgf("Migrating %d files", len(renames))
+
+	if err := db.client.BatchPut(ctx, keys, values); err != nil {
+		return fmt.Errorf("failed to update file index: %w", err)
+	}
+
+	n, err := db.rename(ctx, idPrefix, renames, keyvalue.RenameId)
+	if err != nil {
+		return fmt.Errorf("failed to migrate id index: %w", err)
+	}
+	log.Info().Msgf("Migrated %d storage refs in id index", n)
+
+	n, err = db.rename(ctx, cdxPrefix, renames, keyvalue.RenameCdx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate cdx index: %w", err)
+	}
+	log.Info().Msgf("Migrated %d storage refs in cdx index", n)
+
+	var oldKeys [][]byte
+	for oldName := range renames {
+		oldKeys = append(oldKeys, keyvalue.KeyWithPrefix(oldName, filePrefix))
+	}
+	if err := db.client.BatchDelete(ctx, oldKeys); err != nil {
+		return fmt.Errorf("failed to delete old file index entries: %w", err)
+	}
+	return nil
+}
+
+// rename rewrites the values with prefix that have renamed storage refs and returns the number of values rewritten.
+func (db *DB) rename(ctx context.Context, prefix string, renames map[string]string, fn func([]byte, map[string]string) ([]byte, bool, error)) (int, error) {
+	count := 0
+	var keys [][]byte
+	var values [][]byte
+
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := db.client.BatchPut(ctx, keys, values); err != nil {
+			return err
+		}
+		count += len(keys)
+		keys = keys[:0]
+		values = values[:0]
+		return nil
+	}
+
+	err := db.scanAll(ctx, prefix, func(k []byte, v []byte) error {
+		value, ok, err := fn(v, renames)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to migrate: %s", k)
+			return nil
+		}
+		if !ok {
+			return nil
+		}
+		keys = append(keys, k)
+		values = append(values, value)
+		if len(keys) >= rawkv.MaxRawKVScanLimit {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+// scanAll calls fn with every key-value pair with prefix.
+func (db *DB) scanAll(ctx context.Context, prefix string, fn func(k []byte, v []byte) error) error {
+	key := []byte(prefix)
+	endKey := append([]byte(prefix), 0xff)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	results := make(chan maybeKV)
+	scan := func(k []byte, e []byte) ([][]byte, [][]byte, error) {
+		return db.client.Scan(ctx, k, e, rawkv.MaxRawKVScanLimit)
+	}
+	go repeatScan(scan, key, endKey, false, results, done)
+
+	for kv := range results {
+		if kv.error != nil {
+			return kv.error
+		}
+		if err := fn(kv.k, kv.v); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
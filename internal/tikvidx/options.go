@@ -18,6 +18,8 @@ package tikvidx
 
 import (
 	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
 )
 
 func defaultOptions() *Options {
@@ -25,6 +27,7 @@ func defaultOptions() *Options {
 		BatchMaxSize:    255,
 		BatchMaxWait:    5 * time.Second,
 		BatchMaxRetries: 3,
+		FileIdentity:    index.BaseName,
 	}
 }
 
@@ -35,6 +38,7 @@ type Options struct {
 	ReadOnly        bool
 	PdAddr          []string
 	Database        string
+	FileIdentity    index.FileIdentity
 }
 
 type Option func(opts *Options)
@@ -68,3 +72,10 @@ func WithDatabase(db string) Option {
 		opts.Database = db
 	}
 }
+
+// WithFileIdentity sets how files are identified in the file index.
+func WithFileIdentity(identity index.FileIdentity) Option {
+	return func(opts *Options) {
+		opts.FileIdentity = identity
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tikvidx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/rs/zerolog/log"
+	"github.com/tikv/client-go/v2/rawkv"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reconcile compares the file index to the filesystem.
+//
+// Files that no longer exist at their indexed path are looked for with locate.
+// The path of a file that is found is updated, and if its identity has changed
+// the storage refs of its records are rewritten. The id and cdx entries of a
+// file that is not found are removed along with its file info.
+func (db *DB) Reconcile(ctx context.Context, locate index.FileLocator, dryRun bool) error {
+	var missing []*schema.FileInfo
+	indexed := make(map[string]string)
+
+	err := db.scanAll(ctx, filePrefix, func(_ []byte, v []byte) error {
+		fileInfo := new(schema.FileInfo)
+		if err := proto.Unmarshal(v, fileInfo); err != nil {
+			return err
+		}
+		indexed[fileInfo.GetName()] = fileInfo.GetPath()
+		_, err := os.Stat(fileInfo.GetPath())
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Warn().Err(err).Msgf("Skipping file: %s", fileInfo.GetName())
+			return nil
+		}
+		missing = append(missing, fileInfo)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	located, err := keyvalue.LocateFiles(missing, indexed, locate, dryRun)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+
+	for _, fileInfo := range located.Moved {
+		if err := db.putFileInfo(fileInfo); err != nil {
+			return fmt.Errorf("failed to update file info: %s: %w", fileInfo.GetName(), err)
+		}
+	}
+	if len(located.Renames) > 0 {
+		n, err := db.rename(ctx, idPrefix, located.Renames, keyvalue.RenameId)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite id index: %w", err)
+		}
+		log.Info().Msgf("Rewrote %d storage refs in id index", n)
+
+		n, err = db.rename(ctx, cdxPrefix, located.Renames, keyvalue.RenameCdx)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite cdx index: %w", err)
+		}
+		log.Info().Msgf("Rewrote %d storage refs in cdx index", n)
+
+		// old file infos are removed last so that an interrupted rewrite is resumed on the next run
+		var keys [][]byte
+		for name := range located.Renames {
+			keys = append(keys, keyvalue.KeyWithPrefix(name, filePrefix))
+		}
+		if err := db.client.BatchDelete(ctx, keys); err != nil {
+			return err
+		}
+	}
+	if len(located.Vanished) == 0 {
+		return nil
+	}
+
+	n, err := db.purge(ctx, idPrefix, located.Vanished, keyvalue.IdFilename)
+	if err != nil {
+		return fmt.Errorf("failed to purge id index: %w", err)
+	}
+	log.Info().Msgf("Removed %d entries from id index", n)
+
+	n, err = db.purge(ctx, cdxPrefix, located.Vanished, keyvalue.CdxFilename)
+	if err != nil {
+		return fmt.Errorf("failed to purge cdx index: %w", err)
+	}
+	log.Info().Msgf("Removed %d entries from cdx index", n)
+
+	// file infos are removed last so that an interrupted purge is resumed on the next run
+	var keys [][]byte
+	for name := range located.Vanished {
+		keys = append(keys, keyvalue.KeyWithPrefix(name, filePrefix))
+	}
+	return db.client.BatchDelete(ctx, keys)
+}
+
+// purge deletes the entries with prefix that refer to one of the files and returns the number of entries deleted.
+func (db *DB) purge(ctx context.Context, prefix string, files map[string]bool, filename func([]byte) (string, error)) (int, error) {
+	count := 0
+	var keys [][]byte
+
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := db.client.BatchDelete(ctx, keys); err != nil {
+			return err
+		}
+		count += len(keys)
+		keys = nil
+		return nil
+	}
+
+	err := db.scanAll(ctx, prefix, func(k []byte, v []byte) error {
+		name, err := filename(v)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to get filename: %s", k)
+			return nil
+		}
+		if !files[name] {
+			return nil
+		}
+		keys = append(keys, k)
+		if len(keys) >= rawkv.MaxRawKVScanLimit {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, flush()
+}
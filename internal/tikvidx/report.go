@@ -85,7 +85,7 @@ func (db *DB) GetReport(ctx context.Context, id string) (*schema.Report, error)
 
 func (db *DB) ListReports(ctx context.Context, req index.Request, res chan<- index.ReportResponse) error {
 	key := keyvalue.KeyWithPrefix("", reportPrefix)
-	it, err := newIter(ctx, key, db.client, req, reportPrefix)
+	it, err := newIter(ctx, key, nil, db.client, req, reportPrefix)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tikvidx
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
+	"github.com/tikv/client-go/v2/rawkv"
+	"google.golang.org/protobuf/proto"
+)
+
+// distinctScanLimit is the number of keys of each scan when listing distinct hosts or urls.
+//
+// Keys before the next host or url are skipped within a scan, and a new scan
+// starts at the next host or url if it is beyond the last key of the scan.
+const distinctScanLimit = 128
+
+// ListHosts lists the distinct hosts matching req.
+func (db *DB) ListHosts(ctx context.Context, req index.Request, res chan<- keyvalue.CdxResponse) error {
+	return db.listDistinct(ctx, req, keyvalue.CdxKey.NextHost, res)
+}
+
+// ListUrls lists the distinct urls matching req.
+func (db *DB) ListUrls(ctx context.Context, req index.Request, res chan<- keyvalue.CdxResponse) error {
+	return db.listDistinct(ctx, req, keyvalue.CdxKey.NextUrl, res)
+}
+
+// listDistinct sends the first key matching req and then skips to the key returned by next.
+//
+// Only keys are scanned unless the request has a filter.
+func (db *DB) listDistinct(ctx context.Context, req index.Request, next func(keyvalue.CdxKey) []byte, res chan<- keyvalue.CdxResponse) error {
+	_, portSchemeUserInfo, _ := surt.SplitSSURT(req.Ssurt())
+	key := keyvalue.SearchKeyWithPrefix(req, cdxPrefix)
+	endKey := append(append([]byte{}, key...), 0xff)
+	dateRange := req.DateRange()
+	filter := req.Filter()
+	keysOnly := index.IsEmptyFilter(filter)
+	budget := req.Budget()
+	matchType := req.MatchType()
+
+	var opts []rawkv.RawOption
+	if keysOnly {
+		opts = append(opts, rawkv.ScanKeyOnly())
+	}
+
+	go func() {
+		defer close(res)
+
+		count := 0
+		for {
+			keys, values, err := db.client.Scan(ctx, key, endKey, distinctScanLimit, opts...)
+			if err != nil {
+				res <- keyvalue.CdxResponse{Error: err}
+				return
+			}
+			var skipTo []byte
+			for i, k := range keys {
+				if budget != nil {
+					if err := budget.ScanKey(); err != nil {
+						res <- keyvalue.CdxResponse{Error: err}
+						return
+					}
+				}
+				if skipTo != nil && bytes.Compare(k, skipTo) < 0 {
+					continue
+				}
+				cdxKey := keyvalue.CdxKey(k[len(cdxPrefix):])
+				if !dateRange.Contains(cdxKey.Unix()) {
+					continue
+				}
+				if matchType == index.MatchTypeVerbatim && cdxKey.PortSchemeUserInfo() != portSchemeUserInfo {
+					continue
+				}
+				cdxResponse := keyvalue.CdxResponse{Key: cdxKey}
+				if !keysOnly {
+					if budget != nil {
+						if err := budget.DecodeValue(); err != nil {
+							res <- keyvalue.CdxResponse{Error: err}
+							return
+						}
+					}
+					cdx := new(schema.Cdx)
+					if err := proto.Unmarshal(values[i], cdx); err != nil {
+						cdxResponse = keyvalue.CdxResponse{Error: err}
+					} else if !filter.Eval(cdx) {
+						continue
+					} else {
+						cdxResponse.Value = cdx
+					}
+				}
+				select {
+				case <-ctx.Done():
+					res <- keyvalue.CdxResponse{Error: ctx.Err()}
+					return
+				case res <- cdxResponse:
+					if cdxResponse.GetError() == nil {
+						count++
+					}
+				}
+				if req.Limit() > 0 && count >= req.Limit() {
+					return
+				}
+				// skip the remaining keys of the host or url
+				skipTo = keyvalue.KeyWithPrefix(string(next(cdxKey)), cdxPrefix)
+			}
+			if len(keys) < distinctScanLimit {
+				return
+			}
+			key = append(keys[len(keys)-1], 0x0)
+			if bytes.Compare(skipTo, key) > 0 {
+				key = skipTo
+			}
+		}
+	}()
+	return nil
+}
@@ -2,11 +2,21 @@ package it
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/badger/v4"
+	"github.com/nlnwa/gowarc"
 	"github.com/nlnwa/gowarcserver/index"
 	"github.com/nlnwa/gowarcserver/internal/badgeridx"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
 	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestBadger(t *testing.T) {
@@ -23,6 +33,10 @@ func TestBadger(t *testing.T) {
 	db.FlushBatch()
 
 	runIntegrationTest(t, db)
+	runResumeTest(t, db)
+	runHistogramTest(t, db)
+	runUrlTest(t, db)
+	runLimitsTest(t, db)
 
 	err = db.Delete(context.Background())
 	if err != nil {
@@ -39,3 +53,395 @@ func TestBadger(t *testing.T) {
 		t.Error("expected no records, got:", r)
 	}
 }
+
+func TestBadgerCommitFile(t *testing.T) {
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging(), badgeridx.WithBatchMaxWait(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	path := "../testdata/example.warc.gz"
+	index.NewIndexer(db)(path)
+
+	if _, err := db.GetFileInfo(context.Background(), filepath.Base(path)); err == nil {
+		t.Error("expected file not to be indexed before its records are flushed")
+	}
+	if err := db.Index(path); !errors.Is(err, index.AlreadyIndexedError) {
+		t.Errorf("expected file not to be indexed again while its records are not flushed, got: %v", err)
+	}
+
+	db.FlushBatch()
+
+	if _, err := db.GetFileInfo(context.Background(), filepath.Base(path)); err != nil {
+		t.Errorf("expected file to be indexed after its records are flushed: %v", err)
+	}
+	if err := db.Index(path); !errors.Is(err, index.AlreadyIndexedError) {
+		t.Errorf("got %v, want %v", err, index.AlreadyIndexedError)
+	}
+}
+
+func TestBadgerFileIdentity(t *testing.T) {
+	root := t.TempDir()
+	paths := []string{
+		filepath.Join(root, "a", "example.warc.gz"),
+		filepath.Join(root, "b", "example.warc.gz"),
+	}
+	for _, path := range paths {
+		copyFile(t, "../testdata/example.warc.gz", path)
+	}
+
+	identity, err := index.RelativePath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging(), badgeridx.WithFileIdentity(identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	indexer := index.NewIndexer(db, index.WithFileIdentity(identity))
+	for _, path := range paths {
+		indexer(path)
+	}
+	db.FlushBatch()
+
+	for _, name := range []string{"a/example.warc.gz", "b/example.warc.gz"} {
+		fileInfo, err := db.GetFileInfo(context.Background(), name)
+		if err != nil {
+			t.Fatalf("expected file to be indexed: %s: %v", name, err)
+		}
+		if fileInfo.GetName() != name {
+			t.Errorf("got name %s, want %s", fileInfo.GetName(), name)
+		}
+		path, err := db.ResolvePath(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, _ := filepath.Abs(filepath.Join(root, name)); path != want {
+			t.Errorf("got path %s, want %s", path, want)
+		}
+	}
+}
+
+func TestBadgerMigrateFileIdentity(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a", "example.warc.gz")
+	copyFile(t, "../testdata/example.warc.gz", path)
+
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	index.NewIndexer(db)(path)
+	db.FlushBatch()
+
+	identity, err := index.RelativePath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MigrateFileIdentity(context.Background(), identity); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetFileInfo(context.Background(), "example.warc.gz"); err == nil {
+		t.Error("expected old file identity to be removed")
+	}
+	if _, err := db.GetFileInfo(context.Background(), "a/example.warc.gz"); err != nil {
+		t.Errorf("expected file to be migrated: %v", err)
+	}
+
+	count := 0
+	err = db.IdIndex.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			err := iter.Item().Value(func(val []byte) error {
+				if !strings.HasPrefix(string(val), "warcfile:a/example.warc.gz#") {
+					t.Errorf("expected storage ref to be migrated: %s", val)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Error("expected records in id index")
+	}
+}
+
+func TestBadgerReconcileRenamed(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a", "example.warc.gz")
+	copyFile(t, "../testdata/example.warc.gz", path)
+
+	identity, err := index.RelativePath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging(), badgeridx.WithFileIdentity(identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	index.NewIndexer(db, index.WithFileIdentity(identity))(path)
+	db.FlushBatch()
+
+	// moving the file to another directory changes its identity
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, filepath.Join(root, "b", "example.warc.gz")); err != nil {
+		t.Fatal(err)
+	}
+
+	locate := index.NewFileLocator(identity, []string{root}, 1)
+	if err := db.Reconcile(context.Background(), locate, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetFileInfo(context.Background(), "a/example.warc.gz"); err == nil {
+		t.Error("expected old file identity to be removed")
+	}
+	fileInfo, err := db.GetFileInfo(context.Background(), "b/example.warc.gz")
+	if err != nil {
+		t.Fatalf("expected file to be repointed: %v", err)
+	}
+	if want := filepath.Join(root, "b", "example.warc.gz"); fileInfo.GetPath() != want {
+		t.Errorf("got path %s, want %s", fileInfo.GetPath(), want)
+	}
+
+	checkStorageRefs(t, db.IdIndex, "warcfile:b/example.warc.gz#", idRef)
+	checkStorageRefs(t, db.CdxIndex, "warcfile:b/example.warc.gz#", cdxRef)
+}
+
+func TestBadgerReconcileResumed(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a", "example.warc.gz")
+	copyFile(t, "../testdata/example.warc.gz", path)
+
+	identity, err := index.RelativePath(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging(), badgeridx.WithFileIdentity(identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	index.NewIndexer(db, index.WithFileIdentity(identity))(path)
+	db.FlushBatch()
+
+	moved := filepath.Join(root, "b", "example.warc.gz")
+	if err := os.MkdirAll(filepath.Dir(moved), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reconcile interrupted after writing the new file info and rewriting the id index
+	fileInfo, err := db.GetFileInfo(context.Background(), "a/example.warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileInfo.Path = moved
+	fileInfo.Name = "b/example.warc.gz"
+	key, value, err := keyvalue.MarshalFileInfo(fileInfo, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.FileIndex.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renames := map[string]string{"a/example.warc.gz": "b/example.warc.gz"}
+	err = db.IdIndex.Update(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			val, err := iter.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			val, _, _ = keyvalue.RenameId(val, renames)
+			if err := txn.Set(iter.Item().KeyCopy(nil), val); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locate := index.NewFileLocator(identity, []string{root}, 1)
+	if err := db.Reconcile(context.Background(), locate, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetFileInfo(context.Background(), "a/example.warc.gz"); err == nil {
+		t.Error("expected old file identity to be removed")
+	}
+	if _, err := db.GetFileInfo(context.Background(), "b/example.warc.gz"); err != nil {
+		t.Fatalf("expected file to be repointed: %v", err)
+	}
+	checkStorageRefs(t, db.IdIndex, "warcfile:b/example.warc.gz#", idRef)
+	checkStorageRefs(t, db.CdxIndex, "warcfile:b/example.warc.gz#", cdxRef)
+}
+
+// checkStorageRefs checks that bdb has records and that their storage refs, as
+// returned by ref, have prefix.
+func checkStorageRefs(t *testing.T, bdb *badger.DB, prefix string, ref func(val []byte) (string, error)) {
+	t.Helper()
+	count := 0
+	err := bdb.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			err := iter.Item().Value(func(val []byte) error {
+				storageRef, err := ref(val)
+				if err != nil {
+					return err
+				}
+				if !strings.HasPrefix(storageRef, prefix) {
+					t.Errorf("expected storage ref to be rewritten: %s", storageRef)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Error("expected records of moved file to be kept")
+	}
+}
+
+func idRef(val []byte) (string, error) {
+	return string(val), nil
+}
+
+func cdxRef(val []byte) (string, error) {
+	cdx := new(schema.Cdx)
+	err := proto.Unmarshal(val, cdx)
+	return cdx.GetRef(), err
+}
+
+func copyFile(t *testing.T, src string, dst string) {
+	t.Helper()
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBadgerReconcile(t *testing.T) {
+	tier1 := t.TempDir()
+	tier2 := t.TempDir()
+	moved := filepath.Join(tier1, "moved.warc.gz")
+	vanished := filepath.Join(tier1, "vanished.warc.gz")
+	copyFile(t, "../testdata/example.warc.gz", moved)
+	copyFile(t, "../testdata/example-resource.warc.gz", vanished)
+
+	db, err := badgeridx.NewDB(badgeridx.WithDir(t.TempDir()), badgeridx.WithoutBadgerLogging())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	indexer := index.NewIndexer(db, index.WithRecordTypes(index.DefaultRecordTypes|gowarc.Resource))
+	indexer(moved)
+	indexer(vanished)
+	db.FlushBatch()
+
+	if err := os.Rename(moved, filepath.Join(tier2, "moved.warc.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(vanished); err != nil {
+		t.Fatal(err)
+	}
+
+	locate := index.NewFileLocator(index.BaseName, []string{tier1, tier2}, 0)
+
+	if err := db.Reconcile(context.Background(), locate, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetFileInfo(context.Background(), "vanished.warc.gz"); err != nil {
+		t.Errorf("expected dry run not to remove file: %v", err)
+	}
+
+	if err := db.Reconcile(context.Background(), locate, false); err != nil {
+		t.Fatal(err)
+	}
+	fileInfo, err := db.GetFileInfo(context.Background(), "moved.warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(tier2, "moved.warc.gz"); fileInfo.GetPath() != want {
+		t.Errorf("got path %s, want %s", fileInfo.GetPath(), want)
+	}
+	if _, err := db.GetFileInfo(context.Background(), "vanished.warc.gz"); err == nil {
+		t.Error("expected vanished file to be removed from file index")
+	}
+
+	for _, bdb := range []*badger.DB{db.IdIndex, db.CdxIndex} {
+		count := 0
+		err = bdb.View(func(txn *badger.Txn) error {
+			iter := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer iter.Close()
+			for iter.Rewind(); iter.Valid(); iter.Next() {
+				err := iter.Item().Value(func(val []byte) error {
+					if strings.Contains(string(val), "vanished.warc.gz") {
+						t.Errorf("expected entry of vanished file to be removed: %s", iter.Item().Key())
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 0 {
+			t.Error("expected entries of moved file to be kept")
+		}
+	}
+
+	if err := os.RemoveAll(tier2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reconcile(context.Background(), locate, false); err == nil {
+		t.Error("expected error when a root is not available")
+	}
+}
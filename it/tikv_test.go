@@ -28,6 +28,10 @@ func TestTIKV(t *testing.T) {
 	db.FlushBatch()
 
 	runIntegrationTest(t, db)
+	runResumeTest(t, db)
+	runHistogramTest(t, db)
+	runUrlTest(t, db)
+	runLimitsTest(t, db)
 
 	// delete all records
 	err = db.Delete(context.Background())
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/nlnwa/gowarc"
+)
+
+// PayloadDecoding is how the payload of HTTP messages is decoded when replayed.
+type PayloadDecoding int
+
+const (
+	// DecodeNone leaves the payload as captured.
+	DecodeNone PayloadDecoding = iota
+	// DecodeTransfer removes the chunked transfer encoding of the payload.
+	DecodeTransfer
+	// DecodeContent removes the chunked transfer encoding and the content encoding of the payload.
+	DecodeContent
+)
+
+// ParsePayloadDecoding parses the name of a payload decoding: "none", "transfer" or "content".
+func ParsePayloadDecoding(s string) (PayloadDecoding, error) {
+	switch s {
+	case "", "none":
+		return DecodeNone, nil
+	case "transfer":
+		return DecodeTransfer, nil
+	case "content":
+		return DecodeContent, nil
+	default:
+		return DecodeNone, fmt.Errorf("unknown payload decoding: %s", s)
+	}
+}
+
+// HeaderPrefixArchiveOrig is the prefix of the headers with the original values of
+// headers rewritten when decoding payloads.
+const HeaderPrefixArchiveOrig = "X-Archive-Orig-"
+
+// keepOriginal moves the value of the header field name to the X-Archive-Orig-<name> field.
+func keepOriginal(header http.Header, name string) {
+	if values := header.Values(name); len(values) > 0 {
+		header[http.CanonicalHeaderKey(HeaderPrefixArchiveOrig+name)] = values
+		header.Del(name)
+	}
+}
+
+// HttpHeader returns the header of the HTTP message of block as captured. Unlike the header
+// parsed by the block, it keeps the Transfer-Encoding field and the Content-Length field of
+// chunked messages.
+func HttpHeader(block gowarc.HttpResponseBlock) (http.Header, error) {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(block.ProtocolHeaderBytes())))
+	// skip the status line
+	if _, err := r.ReadLine(); err != nil {
+		return nil, err
+	}
+	header, err := r.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return http.Header(header), nil
+}
+
+// DecodePayload decodes the payload of an HTTP message with header according to decoding, and
+// reports whether the payload was decoded. The Transfer-Encoding, Content-Encoding and
+// Content-Length fields of header are rewritten to match the decoded payload, and the original
+// values are kept in X-Archive-Orig-* fields.
+//
+// Unknown encodings, and payloads that do not look encoded as declared, are left as captured.
+func DecodePayload(header http.Header, payload io.Reader, decoding PayloadDecoding) (io.Reader, bool, error) {
+	if decoding == DecodeNone {
+		return payload, false, nil
+	}
+	decoded := false
+
+	// chunked is the last transfer coding applied
+	if codings := transferCodings(header); len(codings) > 0 && codings[len(codings)-1] == "chunked" {
+		br := bufio.NewReader(payload)
+		payload = br
+		if decodes(br, newChunkedReader) {
+			payload = httputil.NewChunkedReader(br)
+			keepOriginal(header, "Transfer-Encoding")
+			if len(codings) > 1 {
+				header.Set("Transfer-Encoding", strings.Join(codings[:len(codings)-1], ", "))
+			}
+			decoded = true
+		}
+	}
+
+	if decoding == DecodeContent {
+		br := bufio.NewReader(payload)
+		payload = br
+		r, err := contentDecoder(strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding"))), br)
+		if err != nil {
+			return nil, false, err
+		}
+		if r != nil {
+			payload = r
+			keepOriginal(header, "Content-Encoding")
+			decoded = true
+		}
+	}
+
+	// the length of the decoded payload is not known
+	if decoded {
+		keepOriginal(header, "Content-Length")
+	}
+	return payload, decoded, nil
+}
+
+// transferCodings returns the transfer codings of header in the order they were applied.
+func transferCodings(header http.Header) []string {
+	var codings []string
+	for _, value := range header.Values("Transfer-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	return codings
+}
+
+func newChunkedReader(r io.Reader) io.Reader {
+	return httputil.NewChunkedReader(r)
+}
+
+// probeSize is the number of bytes of a payload decoded to check that it is encoded as declared.
+const probeSize = 4096
+
+// contentDecoder returns a reader of the payload of br decoded from the content encoding, or nil
+// if the encoding is unknown or the payload does not start like the encoding.
+func contentDecoder(encoding string, br *bufio.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		magic, err := br.Peek(2)
+		if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+			return nil, nil
+		}
+		return gzip.NewReader(br)
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but is often sent raw
+		b, err := br.Peek(2)
+		if err != nil {
+			return nil, nil
+		}
+		if b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		newReader := func(r io.Reader) io.Reader { return flate.NewReader(r) }
+		if !decodes(br, newReader) {
+			return nil, nil
+		}
+		return newReader(br), nil
+	case "br":
+		newReader := func(r io.Reader) io.Reader { return brotli.NewReader(r) }
+		if !decodes(br, newReader) {
+			return nil, nil
+		}
+		return newReader(br), nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodes reports whether the start of the payload of br is decoded without errors by the
+// reader made by newReader.
+func decodes(br *bufio.Reader, newReader func(io.Reader) io.Reader) bool {
+	b, _ := br.Peek(probeSize)
+	if len(b) == 0 {
+		return false
+	}
+	_, err := io.Copy(io.Discard, newReader(bytes.NewReader(b)))
+	// the probe of a longer payload ends unexpectedly
+	return err == nil || err == io.ErrUnexpectedEOF && len(b) == probeSize
+}
@@ -0,0 +1,237 @@
+package loader
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func brotlied(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func deflated(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func chunked(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	w := httputil.NewChunkedWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func TestDecodePayload(t *testing.T) {
+	const body = "hello, world"
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		payload  []byte
+		decoding PayloadDecoding
+		want     []byte
+		decoded  bool
+		// wantHeader are the expected header fields after decoding, "" if absent
+		wantHeader map[string]string
+	}{
+		{
+			name:     "none",
+			header:   map[string]string{"Transfer-Encoding": "chunked"},
+			payload:  chunked(t, []byte(body)),
+			decoding: DecodeNone,
+			want:     chunked(t, []byte(body)),
+			wantHeader: map[string]string{
+				"Transfer-Encoding": "chunked",
+			},
+		},
+		{
+			name:     "de-chunk",
+			header:   map[string]string{"Transfer-Encoding": "chunked", "Content-Encoding": "gzip"},
+			payload:  chunked(t, gzipped(t, body)),
+			decoding: DecodeTransfer,
+			want:     gzipped(t, body),
+			decoded:  true,
+			wantHeader: map[string]string{
+				"Transfer-Encoding":                "",
+				"X-Archive-Orig-Transfer-Encoding": "chunked",
+				"Content-Encoding":                 "gzip",
+			},
+		},
+		{
+			name:     "de-chunk and gunzip",
+			header:   map[string]string{"Transfer-Encoding": "chunked", "Content-Encoding": "gzip"},
+			payload:  chunked(t, gzipped(t, body)),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			decoded:  true,
+			wantHeader: map[string]string{
+				"Transfer-Encoding":                "",
+				"X-Archive-Orig-Transfer-Encoding": "chunked",
+				"Content-Encoding":                 "",
+				"X-Archive-Orig-Content-Encoding":  "gzip",
+			},
+		},
+		{
+			name:     "de-chunk after gzip",
+			header:   map[string]string{"Transfer-Encoding": "gzip, chunked"},
+			payload:  chunked(t, gzipped(t, body)),
+			decoding: DecodeTransfer,
+			want:     gzipped(t, body),
+			decoded:  true,
+			wantHeader: map[string]string{
+				"Transfer-Encoding":                "gzip",
+				"X-Archive-Orig-Transfer-Encoding": "gzip, chunked",
+			},
+		},
+		{
+			name:     "not chunked",
+			header:   map[string]string{"Transfer-Encoding": "chunked", "Content-Length": "12"},
+			payload:  []byte(body),
+			decoding: DecodeTransfer,
+			want:     []byte(body),
+			wantHeader: map[string]string{
+				"Transfer-Encoding": "chunked",
+				"Content-Length":    "12",
+			},
+		},
+		{
+			name:     "brotli",
+			header:   map[string]string{"Content-Encoding": "br", "Content-Length": "10"},
+			payload:  brotlied(t, body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			decoded:  true,
+			wantHeader: map[string]string{
+				"Content-Encoding":                "",
+				"X-Archive-Orig-Content-Encoding": "br",
+				"Content-Length":                  "",
+				"X-Archive-Orig-Content-Length":   "10",
+			},
+		},
+		{
+			name:     "not gzipped",
+			header:   map[string]string{"Content-Encoding": "gzip", "Content-Length": "12"},
+			payload:  []byte(body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			wantHeader: map[string]string{
+				"Content-Encoding": "gzip",
+				"Content-Length":   "12",
+			},
+		},
+		{
+			name:     "raw deflate",
+			header:   map[string]string{"Content-Encoding": "deflate"},
+			payload:  deflated(t, body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			decoded:  true,
+			wantHeader: map[string]string{
+				"Content-Encoding":                "",
+				"X-Archive-Orig-Content-Encoding": "deflate",
+			},
+		},
+		{
+			name:     "not brotli",
+			header:   map[string]string{"Content-Encoding": "br", "Content-Length": "12"},
+			payload:  []byte(body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			wantHeader: map[string]string{
+				"Content-Encoding": "br",
+				"Content-Length":   "12",
+			},
+		},
+		{
+			name:     "not deflated",
+			header:   map[string]string{"Content-Encoding": "deflate", "Content-Length": "12"},
+			payload:  []byte(body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			wantHeader: map[string]string{
+				"Content-Encoding": "deflate",
+				"Content-Length":   "12",
+			},
+		},
+		{
+			name:     "unknown encoding",
+			header:   map[string]string{"Content-Encoding": "compress"},
+			payload:  []byte(body),
+			decoding: DecodeContent,
+			want:     []byte(body),
+			wantHeader: map[string]string{
+				"Content-Encoding": "compress",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			r, decoded, err := DecodePayload(header, bytes.NewReader(tt.payload), tt.decoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded != tt.decoded {
+				t.Errorf("got decoded %v, want %v", decoded, tt.decoded)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got payload %q, want %q", got, tt.want)
+			}
+			for k, v := range tt.wantHeader {
+				if got := header.Get(k); got != v {
+					t.Errorf("got %s %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
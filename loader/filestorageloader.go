@@ -17,8 +17,12 @@
 package loader
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 
@@ -41,10 +45,18 @@ func (f FileStorageLoader) Load(ctx context.Context, storageRef string) (record
 	}
 	log.Debug().Str("storageRef", storageRef).Msgf("Loading record from file: %s, offset: %v", filePath, offset)
 
-	wf, err := gowarc.NewWarcFileReader(filePath, offset,
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize warc reader: %s#%d, %w", filePath, offset, err)
+	}
+	// the block of uncompressed records is read from the file at any offset
+	blockOffset, uncompressed := findBlockOffset(file, offset)
+
+	wf, err := gowarc.NewWarcFileReaderFromStream(file, offset,
 		gowarc.WithSyntaxErrorPolicy(gowarc.ErrIgnore),
 		gowarc.WithSpecViolationPolicy(gowarc.ErrIgnore))
 	if err != nil {
+		_ = file.Close()
 		return nil, fmt.Errorf("failed to initialize warc reader: %s#%d, %w", filePath, offset, err)
 	}
 
@@ -58,11 +70,72 @@ func (f FileStorageLoader) Load(ctx context.Context, storageRef string) (record
 		log.Error().Msgf("%s, offset %v\n", err, offset)
 		return nil, fmt.Errorf("failed to read record: %s#%d: %w", filePath, offset, err)
 	}
+	if uncompressed {
+		if size, err := record.WarcHeader().GetInt64(gowarc.ContentLength); err == nil {
+			record = sectionRecord{WarcRecord: record, block: io.NewSectionReader(file, blockOffset, size)}
+		}
+	}
 	return
 }
 
-// parseStorageRef parses a storageRef (eg. warcfile:filename#offset) into parts.
+// SeekableRecord is a record of an uncompressed warc file. The block of the
+// record can be read at any offset without reading the preceding bytes.
+type SeekableRecord interface {
+	gowarc.WarcRecord
+	// BlockSection returns the block of the record as stored in the warc file.
+	BlockSection() *io.SectionReader
+}
+
+type sectionRecord struct {
+	gowarc.WarcRecord
+	block *io.SectionReader
+}
+
+func (r sectionRecord) BlockSection() *io.SectionReader {
+	return io.NewSectionReader(r.block, 0, r.block.Size())
+}
+
+// maxWarcHeaderSize is the maximum size of a WARC header searched for the start of the block.
+const maxWarcHeaderSize = 1024 * 1024
+
+// findBlockOffset returns the offset in file of the block of the uncompressed record at offset.
+// It returns false if the record is compressed or the end of the WARC header is not found.
+func findBlockOffset(file io.ReaderAt, offset int64) (int64, bool) {
+	r := bufio.NewReader(io.NewSectionReader(file, offset, maxWarcHeaderSize))
+	magic, err := r.Peek(5)
+	if err != nil || !bytes.Equal(magic, []byte("WARC/")) {
+		return 0, false
+	}
+	n := offset
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return 0, false
+		}
+		n += int64(len(line))
+		if len(line) <= 2 && len(bytes.TrimRight(line, "\r\n")) == 0 {
+			return n, true
+		}
+	}
+}
+
+// parseStorageRef parses a storageRef (eg. warcfile:filename#offset) into the path of the file and offset.
 func (f FileStorageLoader) parseStorageRef(storageRef string) (filename string, offset int64, err error) {
+	filename, offset, err = ParseStorageRef(storageRef)
+	if err != nil {
+		return
+	}
+	if f.FilePathResolver != nil {
+		filename, err = f.FilePathResolver.ResolvePath(filename)
+	}
+	return
+}
+
+// ParseStorageRef parses a storageRef (eg. warcfile:filename#offset) into parts.
+//
+// The filename is the identity of the file in the file index and may contain
+// path separators, so the offset delimiter is the last '#' of the storage ref.
+func ParseStorageRef(storageRef string) (filename string, offset int64, err error) {
 	n := strings.IndexRune(storageRef, ':')
 	if n == -1 {
 		err = fmt.Errorf("invalid storage ref '%s', missing scheme delimiter ':'", storageRef)
@@ -73,19 +146,16 @@ func (f FileStorageLoader) parseStorageRef(storageRef string) (filename string,
 		err = fmt.Errorf("invalid storage ref '%s', scheme must be \"warcfile\", was: %s", storageRef, scheme)
 		return
 	}
-	storageRef = storageRef[n+1:]
-	n = strings.IndexRune(storageRef, '#')
+	ref := storageRef[n+1:]
+	n = strings.LastIndexByte(ref, '#')
 	if n == -1 {
 		err = fmt.Errorf("invalid storage ref '%s', missing offset delimiter '#'", storageRef)
 		return
 	}
-	filename = storageRef[:n]
-	offset, err = strconv.ParseInt(storageRef[n+1:], 0, 64)
+	filename = ref[:n]
+	offset, err = strconv.ParseInt(ref[n+1:], 0, 64)
 	if err != nil {
 		err = fmt.Errorf("invalid storage ref '%s', failed to parse offset: %w", storageRef, err)
 	}
-	if f.FilePathResolver != nil {
-		filename, err = f.FilePathResolver.ResolvePath(filename)
-	}
 	return
 }
@@ -0,0 +1,54 @@
+package loader
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestFileStorageLoaderSeekable(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageRef string
+		seekable   bool
+	}{
+		{name: "uncompressed", storageRef: "warcfile:../testdata/example.warc#1197", seekable: true},
+		{name: "compressed", storageRef: "warcfile:../testdata/example.warc.gz#784", seekable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			record, err := FileStorageLoader{}.Load(ctx, tt.storageRef)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer record.Close()
+
+			sr, ok := record.(SeekableRecord)
+			if ok != tt.seekable {
+				t.Fatalf("got seekable %v, want %v", ok, tt.seekable)
+			}
+			if !ok {
+				return
+			}
+			section, err := io.ReadAll(sr.BlockSection())
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := record.Block().RawBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(section, block) {
+				t.Errorf("got block section %q, want %q", section, block)
+			}
+		})
+	}
+}
@@ -38,10 +38,19 @@ type WarcLoader interface {
 	LoadByStorageRef(context.Context, string) (gowarc.WarcRecord, error)
 }
 
+// RevisitResolver resolves the storage ref of the record a revisit record refers
+// to by its WARC-Refers-To-Target-URI and WARC-Refers-To-Date.
+type RevisitResolver interface {
+	ResolveRevisit(ctx context.Context, targetURI string, date string) (storageRef string, err error)
+}
+
 type Loader struct {
 	StorageRefResolver
 	RecordLoader
-	NoUnpack bool
+	// RevisitResolver resolves revisit records without WARC-Refers-To. If nil,
+	// loading such records fails with ErrResolveRevisit.
+	RevisitResolver RevisitResolver
+	NoUnpack        bool
 }
 
 type ErrResolveRevisit struct {
@@ -103,21 +112,32 @@ func (l *Loader) LoadByStorageRef(ctx context.Context, storageRef string) (gowar
 			if warcRefersToDate == "" {
 				warcRefersToDate = record.WarcHeader().Get(gowarc.WarcDate)
 			}
-			return nil, ErrResolveRevisit{
+			errResolveRevisit := ErrResolveRevisit{
 				Profile:   record.WarcHeader().Get(gowarc.WarcProfile),
 				TargetURI: warcRefersToTargetURI,
 				Date:      warcRefersToDate,
 			}
+			if l.RevisitResolver == nil {
+				return nil, errResolveRevisit
+			}
+			storageRef, err = l.RevisitResolver.ResolveRevisit(ctx, warcRefersToTargetURI, warcRefersToDate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errResolveRevisit, err)
+			}
+			if storageRef == "" {
+				return nil, errResolveRevisit
+			}
+		} else {
+			storageRef, err = l.Resolve(ctx, warcRefersTo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve WARC-Refers-To [%s]: %w", warcRefersTo, err)
+			}
+			if storageRef == "" {
+				return nil, ErrWarcRefersToNotFound{WarcRefersTo: warcRefersTo}
+			}
 		}
 
 		var revisitOf gowarc.WarcRecord
-		storageRef, err = l.Resolve(ctx, warcRefersTo)
-		if err != nil {
-			return nil, fmt.Errorf("failed to resolve WARC-Refers-To [%s]: %w", warcRefersTo, err)
-		}
-		if storageRef == "" {
-			return nil, ErrWarcRefersToNotFound{WarcRefersTo: warcRefersTo}
-		}
 		revisitOf, err = l.RecordLoader.Load(ctx, storageRef)
 		if err != nil {
 			return nil, err
@@ -17,6 +17,7 @@
 package api
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -25,6 +26,7 @@ import (
 	"strings"
 
 	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
 	"github.com/nlnwa/gowarcserver/surt"
 	"github.com/nlnwa/gowarcserver/timestamp"
 	whatwgUrl "github.com/nlnwa/whatwg-url/url"
@@ -56,14 +58,13 @@ var matchTypes = []string{
 const (
 	OutputCdxj = "cdxj"
 	OutputJson = "json"
-
-	// The following outputs is not implemented:
-	// OutputLink = "link"
-	// OutputText = "text"
-
+	OutputLink = "link"
+	OutputText = "text"
+	OutputCsv  = "csv"
+	OutputPb   = "pb"
 )
 
-var outputs = []string{OutputCdxj, OutputJson}
+var outputs = []string{OutputCdxj, OutputJson, OutputLink, OutputText, OutputCsv, OutputPb}
 
 const (
 	ParamMatchType = "matchType"
@@ -76,10 +77,36 @@ const (
 	ParamOutput    = "output"
 	ParamFilter    = "filter"
 	ParamFields    = "fields"
+	ParamResumeKey = "resumeKey"
+	ParamCollapse  = "collapse"
 )
 
+// HeaderResumeKey is the trailer holding the resume key of a search that has more results.
+const HeaderResumeKey = "Resume-Key"
+
+// HeaderStreamStatus is the trailer holding the status code of a streamed response.
+// It is 200 if all results were written, else the status of the first error.
+const HeaderStreamStatus = "Stream-Status"
+
+// EncodeResumeKey encodes the key of the last result of a search as an opaque resume key.
+func EncodeResumeKey(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+// DecodeResumeKey decodes an opaque resume key into the key of the last result of a search.
+func DecodeResumeKey(resumeKey string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(resumeKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid resume key: %w", err)
+	}
+	return string(b), nil
+}
+
 type SearchRequest struct {
 	FilterMap map[string]string
+	// FilterFields are fields derived from cdx records that filters match by name
+	// instead of the cdx fields.
+	FilterFields map[string]func(*schema.Cdx) string
 
 	url.Values
 
@@ -93,6 +120,11 @@ type SearchRequest struct {
 	closest   string
 	output    string
 	fields    []string
+	resumeKey string
+	collapse  *Collapse
+	limits    Limits
+	// limited is true if the limit was lowered by limits
+	limited bool
 }
 
 func (c *SearchRequest) Url() *whatwgUrl.Url {
@@ -135,6 +167,17 @@ func (c *SearchRequest) Fields() []string {
 	return c.fields
 }
 
+func (c *SearchRequest) ResumeKey() string {
+	return c.resumeKey
+}
+
+func (c *SearchRequest) Collapse() index.Collapser {
+	if c.collapse == nil {
+		return nil
+	}
+	return c.collapse.Collapser()
+}
+
 func (c *SearchRequest) SetLimit(limit int) {
 	c.limit = limit
 }
@@ -143,7 +186,8 @@ func (c *SearchRequest) SetMatchType(matchType index.MatchType) {
 	c.matchType = matchType
 }
 
-var schemeRegExp = regexp.MustCompile(`^[a-z][a-z0-9+\-.]+(:.*)`)
+// SchemeRegExp matches urls that start with a scheme.
+var SchemeRegExp = regexp.MustCompile(`^[a-z][a-z0-9+\-.]+(:.*)`)
 
 func Parse(values url.Values) (req *SearchRequest, err error) {
 	req = new(SearchRequest)
@@ -181,7 +225,15 @@ func (c *SearchRequest) Parse(values url.Values) error {
 	// URL
 	urlStr := values.Get(ParamUrl)
 	if urlStr != "" {
-		if !schemeRegExp.MatchString(urlStr) {
+		// wildcards select the match type, i.e. "*.example.com" (domain) or "example.com/path/*" (prefix)
+		if wildcardMatchType, u, ok := parseWildcard(urlStr); ok {
+			if matchType != "" && c.matchType != wildcardMatchType {
+				return fmt.Errorf("url %s conflicts with %s=%s", urlStr, ParamMatchType, matchType)
+			}
+			c.matchType = wildcardMatchType
+			urlStr = u
+		}
+		if !SchemeRegExp.MatchString(urlStr) {
 			urlStr = "http://" + urlStr
 		}
 		u, err := whatwgUrl.Parse(urlStr)
@@ -241,6 +293,18 @@ func (c *SearchRequest) Parse(values url.Values) error {
 		}
 	}
 
+	// Resume key
+	resumeKey := values.Get(ParamResumeKey)
+	if resumeKey != "" {
+		if c.sort == index.SortClosest {
+			return fmt.Errorf("%s is not valid with %s=%s", ParamResumeKey, ParamSort, SortClosest)
+		}
+		c.resumeKey, err = DecodeResumeKey(resumeKey)
+		if err != nil {
+			return err
+		}
+	}
+
 	output := values.Get(ParamOutput)
 	if output != "" {
 		if !slices.Contains(outputs, output) {
@@ -251,7 +315,22 @@ func (c *SearchRequest) Parse(values url.Values) error {
 
 	filter, ok := values[ParamFilter]
 	if ok {
-		c.filter = ParseFilter(filter, c.FilterMap)
+		c.filter, err = ParseFilterFields(filter, c.FilterMap, c.FilterFields)
+		if err != nil {
+			return err
+		}
+	}
+
+	collapse := values.Get(ParamCollapse)
+	if collapse != "" {
+		// collapsing merges adjacent results, which are not alike when sorted by closeness
+		if c.sort == index.SortClosest {
+			return fmt.Errorf("%s is not valid with %s=%s", ParamCollapse, ParamSort, SortClosest)
+		}
+		c.collapse, err = ParseCollapse(collapse, c.FilterMap)
+		if err != nil {
+			return err
+		}
 	}
 
 	fields := values.Get(ParamFields)
@@ -262,13 +341,74 @@ func (c *SearchRequest) Parse(values url.Values) error {
 	return nil
 }
 
+// parseWildcard returns the match type of a url with a wildcard and the url without the wildcard.
+func parseWildcard(u string) (index.MatchType, string, bool) {
+	switch {
+	case strings.HasPrefix(u, "*."):
+		return index.MatchTypeDomain, u[2:], true
+	case strings.HasSuffix(u, "*"):
+		return index.MatchTypePrefix, u[:len(u)-1], true
+	default:
+		return index.MatchTypeExact, u, false
+	}
+}
+
+// replayableRecordTypes are the record types that can be replayed.
+var replayableRecordTypes = []string{"response", "revisit", "resource"}
+
+// replayableFilter only matches records of replayable record types.
+var replayableFilter = func() Filter {
+	terms := make([]string, len(replayableRecordTypes))
+	for i, recordType := range replayableRecordTypes {
+		terms[i] = "=srt:" + recordType
+	}
+	f, err := ParseFilter([]string{strings.Join(terms, orSeparator)}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
+// MementoRequest returns a request for the replayable captures of url sorted by time.
+func MementoRequest(url *whatwgUrl.Url) *SearchRequest {
+	return &SearchRequest{
+		whatwgUrl: url,
+		ssurt:     surt.UrlToSsurt(url),
+		filter:    replayableFilter,
+		matchType: index.MatchTypeExact,
+	}
+}
+
 func ClosestRequest(closest string, url *whatwgUrl.Url) *SearchRequest {
 	return &SearchRequest{
 		whatwgUrl: url,
 		ssurt:     surt.UrlToSsurt(url),
 		limit:     10,
+		filter:    replayableFilter,
 		sort:      index.SortClosest,
 		closest:   closest,
 		matchType: index.MatchTypeVerbatim,
 	}
 }
+
+// urlCollapse collapses adjacent captures of the same url.
+var urlCollapse = func() *Collapse {
+	c, err := ParseCollapse("uri", nil)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// PrefixRequest returns a request for the replayable captures matching filter of urls with
+// the path of url as prefix, where adjacent captures of the same url are collapsed.
+func PrefixRequest(url *whatwgUrl.Url, filter Filter) *SearchRequest {
+	f := append(Filter{}, replayableFilter...)
+	return &SearchRequest{
+		whatwgUrl: url,
+		ssurt:     surt.UrlToSsurt(url),
+		filter:    append(f, filter...),
+		collapse:  urlCollapse,
+		matchType: index.MatchTypePrefix,
+	}
+}
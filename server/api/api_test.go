@@ -84,6 +84,111 @@ func TestParse(t *testing.T) {
 				sort:    index.SortClosest,
 			},
 		},
+		// resume key should be decoded
+		{
+			query: map[string][]string{
+				"url":       {"http://example.com/"},
+				"resumeKey": {EncodeResumeKey("com,example,/ 20200101000000 :http: response")},
+			},
+			want: &SearchRequest{
+				resumeKey: "com,example,/ 20200101000000 :http: response",
+			},
+		},
+		// invalid resume key should error
+		{
+			query: map[string][]string{
+				"url":       {"http://example.com/"},
+				"resumeKey": {"not base64!"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("invalid resume key"),
+		},
+		// resume key is not valid with sort closest
+		{
+			query: map[string][]string{
+				"url":       {"http://example.com/"},
+				"closest":   {"20020101000000"},
+				"sort":      {"closest"},
+				"resumeKey": {EncodeResumeKey("com,example,/ 20200101000000 :http: response")},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("resume key is not valid with sort closest"),
+		},
+		// invalid filter should error
+		{
+			query: map[string][]string{
+				"url":    {"http://example.com/"},
+				"filter": {"hsc"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("invalid filter"),
+		},
+		// domain wildcard should set match type
+		{
+			query: map[string][]string{
+				"url": {"*.example.com"},
+			},
+			want: &SearchRequest{
+				matchType: index.MatchTypeDomain,
+			},
+		},
+		// prefix wildcard should set match type
+		{
+			query: map[string][]string{
+				"url": {"example.com/path/*"},
+			},
+			want: &SearchRequest{
+				matchType: index.MatchTypePrefix,
+			},
+		},
+		// wildcard should not conflict with match type
+		{
+			query: map[string][]string{
+				"url":       {"example.com/path/*"},
+				"matchType": {"exact"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("url conflicts with matchType"),
+		},
+		// collapse should be parsed
+		{
+			query: map[string][]string{
+				"url":      {"http://example.com/"},
+				"collapse": {"sts:8"},
+			},
+			want: &SearchRequest{
+				collapse: &Collapse{field: cdxFields.ByName("sts"), n: 8},
+			},
+		},
+		// collapse on unknown field should error
+		{
+			query: map[string][]string{
+				"url":      {"http://example.com/"},
+				"collapse": {"nope"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("invalid collapse"),
+		},
+		// collapse length must be positive
+		{
+			query: map[string][]string{
+				"url":      {"http://example.com/"},
+				"collapse": {"sts:0"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("invalid collapse"),
+		},
+		// collapse should not be valid with sort closest
+		{
+			query: map[string][]string{
+				"url":      {"http://example.com/"},
+				"sort":     {"closest"},
+				"closest":  {"20200101"},
+				"collapse": {"dig"},
+			},
+			want: &SearchRequest{},
+			err:  errors.New("collapse is not valid with sort=closest"),
+		},
 	}
 
 	for i, test := range tests {
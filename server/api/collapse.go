@@ -0,0 +1,82 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Collapse collapses adjacent results that have the same value of a field,
+// optionally comparing only the first n characters of the value.
+//
+// Examples: "dig" (one result per digest in a row), "sts:8" (one result per day).
+type Collapse struct {
+	field protoreflect.FieldDescriptor
+	n     int
+}
+
+// ParseCollapse parses a collapse parameter of the form "field" or "field:n".
+func ParseCollapse(s string, remap map[string]string) (*Collapse, error) {
+	name, length, hasLength := strings.Cut(s, ":")
+	if rename, ok := remap[name]; ok {
+		name = rename
+	}
+	field, err := CdxField(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collapse: %w", err)
+	}
+	c := &Collapse{field: field}
+	if hasLength {
+		c.n, err = strconv.Atoi(length)
+		if err != nil || c.n <= 0 {
+			return nil, fmt.Errorf("invalid collapse: length must be a positive integer, was: %s", length)
+		}
+	}
+	return c, nil
+}
+
+// Collapser returns a new collapser that keeps track of the previous result.
+func (c *Collapse) Collapser() index.Collapser {
+	return &collapser{field: c.field, n: c.n}
+}
+
+type collapser struct {
+	field protoreflect.FieldDescriptor
+	n     int
+	prev  string
+	valid bool
+}
+
+// Collapse returns true if the value of cdx is equal to the value of the previous result.
+func (c *collapser) Collapse(cdx *schema.Cdx) bool {
+	value := CdxFieldValue(cdx, c.field)
+	if c.n > 0 && len(value) > c.n {
+		value = value[:c.n]
+	}
+	if c.valid && value == c.prev {
+		return true
+	}
+	c.prev = value
+	c.valid = true
+	return false
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/schema"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCollapse(t *testing.T) {
+	ts := func(s string) *timestamppb.Timestamp {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return timestamppb.New(tm)
+	}
+	cdxs := []*schema.Cdx{
+		{Uri: "http://example.com/", Dig: "sha1:A", Sts: ts("2020-01-01T10:00:00Z")},
+		{Uri: "http://example.com/", Dig: "sha1:A", Sts: ts("2020-01-01T11:00:00Z")},
+		{Uri: "http://example.com/", Dig: "sha1:B", Sts: ts("2020-01-02T10:00:00Z")},
+		{Uri: "http://example.com/", Dig: "sha1:A", Sts: ts("2020-02-01T10:00:00Z")},
+	}
+
+	tests := []struct {
+		collapse string
+		want     []int
+	}{
+		{collapse: "dig", want: []int{0, 2, 3}},
+		{collapse: "uri", want: []int{0}},
+		{collapse: "sts", want: []int{0, 1, 2, 3}},
+		{collapse: "sts:8", want: []int{0, 2, 3}},
+		{collapse: "sts:6", want: []int{0, 3}},
+		{collapse: "digest", want: []int{0, 2, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.collapse, func(t *testing.T) {
+			c, err := ParseCollapse(test.collapse, map[string]string{"digest": "dig"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			collapser := c.Collapser()
+			var got []int
+			for i, cdx := range cdxs {
+				if !collapser.Collapse(cdx) {
+					got = append(got, i)
+				}
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var cdxFields = (&schema.Cdx{}).ProtoReflect().Descriptor().Fields()
+
+// CdxField returns the descriptor of the cdx field with name.
+func CdxField(name string) (protoreflect.FieldDescriptor, error) {
+	fd := cdxFields.ByName(protoreflect.Name(name))
+	if fd == nil {
+		return nil, fmt.Errorf("unknown field: %s", name)
+	}
+	return fd, nil
+}
+
+// CdxFieldValue returns the value of the field of c as a string.
+// Timestamps are formatted as 14 digit timestamps (yyyyMMddHHmmss).
+func CdxFieldValue(c *schema.Cdx, fd protoreflect.FieldDescriptor) string {
+	m := c.ProtoReflect()
+	if fd.Kind() == protoreflect.MessageKind {
+		if !m.Has(fd) {
+			return ""
+		}
+		if ts, ok := m.Get(fd).Message().Interface().(*timestamppb.Timestamp); ok {
+			return timestamp.TimeTo14(ts.AsTime())
+		}
+	}
+	return m.Get(fd).String()
+}
+
+// MediaType returns the media type of the content type ct without parameters, e.g.
+// "text/html" of "text/html; charset=utf-8".
+func MediaType(ct string) string {
+	mediaType, _, _ := strings.Cut(ct, ";")
+	return strings.TrimSpace(mediaType)
+}
+
+// UrlKey returns the urlkey of the classic cdx formats of cdx, e.g. "com,example)/", or
+// the ssurt of cdx if its uri has no urlkey.
+func UrlKey(cdx *schema.Cdx) string {
+	urlKey, err := surt.UrlKey(cdx.GetUri())
+	if err != nil {
+		return cdx.GetSsu()
+	}
+	return urlKey
+}
+
+// textValueEscaper percent-encodes the whitespace separating the values of cdx lines.
+var textValueEscaper = strings.NewReplacer(" ", "%20", "\t", "%09", "\r", "%0D", "\n", "%0A")
+
+// TextValue returns v escaped as a value of a space separated cdx line.
+func TextValue(v string) string {
+	return textValueEscaper.Replace(v)
+}
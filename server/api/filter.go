@@ -17,10 +17,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/surt"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
@@ -30,10 +34,38 @@ const (
 	OpContains Op = iota
 	OpExact
 	OpRegexp
+	OpLess
+	OpLessOrEqual
+	OpGreater
+	OpGreaterOrEqual
 )
 
+// orSeparator separates the alternatives of a filter.
+const orSeparator = "||"
+
+// Filter is a list of filters that all must match.
+//
+// The grammar of a single filter is:
+//
+//	filter     = term *( "||" term )
+//	term       = [ "!" ] ( match / comparison )
+//	match      = [ "=" / "~" ] field ":" value
+//	comparison = field ( "<" / "<=" / ">" / ">=" ) value
+//
+// A match is a substring match, an exact match ("=") or a regular expression match ("~").
+// A comparison is numeric for numeric fields, and compares 14 digit timestamps for
+// timestamp fields where the value may be a prefix of a timestamp (e.g. "sts>=2020").
+// A filter matches if any of its terms matches. Every "||" separates terms unless its first
+// '|' is escaped by a backslash, which is kept in the value. So the term "~mct:a\||b" is the
+// regular expression matching "a|" or "b", while "~mct:a||b" is the two terms "~mct:a" and
+// "b". An empty alternative of a regular expression can be written as "|(?:)|".
 type Filter []filter
 
+// Empty returns true if the filter has no conditions and so passes every cdx record.
+func (f Filter) Empty() bool {
+	return len(f) == 0
+}
+
 func (f Filter) Eval(c *schema.Cdx) bool {
 	for _, ff := range f {
 		if !ff.eval(c) {
@@ -43,89 +75,280 @@ func (f Filter) Eval(c *schema.Cdx) bool {
 	return true
 }
 
-func ParseFilter(filterStrings []string, remap map[string]string) Filter {
+// ParseFilter parses filterStrings into a Filter. Field names are renamed according to remap.
+func ParseFilter(filterStrings []string, remap map[string]string) (Filter, error) {
+	return ParseFilterFields(filterStrings, remap, nil)
+}
+
+// ParseFilterFields parses filterStrings into a Filter like ParseFilter where the fields
+// with a name in fields match the string value derived from a cdx record by fields[name].
+func ParseFilterFields(filterStrings []string, remap map[string]string, fields map[string]func(*schema.Cdx) string) (Filter, error) {
 	var filters Filter
 
 	for _, f := range filterStrings {
-		not := false
-		if f[0] == '!' {
-			f = f[1:]
-			not = true
+		if f == "" {
+			return nil, errors.New("invalid filter: filter is empty")
 		}
-		var op Op
-		switch f[0] {
-		case '=':
-			f = f[1:]
-			op = OpExact
-		case '~':
-			f = f[1:]
-			op = OpRegexp
-		default:
-			op = OpContains
+		var alternatives filter
+		for _, s := range splitTerms(f) {
+			t, err := parseTerm(s, remap, fields)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter '%s': %w", f, err)
+			}
+			alternatives = append(alternatives, t)
 		}
+		filters = append(filters, alternatives)
+	}
 
-		t := strings.SplitN(f, ":", 2)
-		field := t[0]
-		if rename, ok := remap[field]; ok {
-			field = rename
+	return filters, nil
+}
+
+// splitTerms splits a filter into its terms at the separators that are not escaped.
+func splitTerms(f string) []string {
+	var terms []string
+	start := 0
+	for i := 0; i+len(orSeparator) <= len(f); i++ {
+		if f[i:i+len(orSeparator)] != orSeparator || isEscaped(f, i) {
+			continue
 		}
-		value := t[1]
+		terms = append(terms, f[start:i])
+		start = i + len(orSeparator)
+		i = start - 1
+	}
+	return append(terms, f[start:])
+}
 
-		filter := filter{
-			field:       field,
-			filterValue: value,
-			invert:      not,
+// isEscaped returns true if the character at i of s is preceded by an odd number of backslashes.
+func isEscaped(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func parseTerm(s string, remap map[string]string, fields map[string]func(*schema.Cdx) string) (t term, err error) {
+	if strings.HasPrefix(s, "!") {
+		s = s[1:]
+		t.invert = true
+	}
+	t.op = OpContains
+	prefixed := true
+	switch {
+	case strings.HasPrefix(s, "="):
+		t.op = OpExact
+	case strings.HasPrefix(s, "~"):
+		t.op = OpRegexp
+	default:
+		prefixed = false
+	}
+	if prefixed {
+		s = s[1:]
+	}
+
+	i := strings.IndexAny(s, ":<>")
+	if i <= 0 {
+		return t, errors.New("expected field:value or a comparison like field>=value")
+	}
+	name := s[:i]
+	if get, ok := fields[name]; ok {
+		t.field = field{kind: kindString, get: func(c *schema.Cdx) (string, bool) { return get(c), true }}
+	} else {
+		if rename, ok := remap[name]; ok {
+			name = rename
+		}
+		t.field, err = filterField(name)
+		if err != nil {
+			return t, err
 		}
+	}
 
-		switch op {
-		case OpContains:
-			filter.matcher = func(filterValue, fieldValue string) bool {
-				return strings.Contains(fieldValue, filterValue)
+	switch {
+	case s[i] == ':':
+		t.value = s[i+1:]
+	case prefixed:
+		return t, errors.New("'=' and '~' can not be combined with a comparison")
+	case strings.HasPrefix(s[i:], "<="):
+		t.op, t.value = OpLessOrEqual, s[i+2:]
+	case strings.HasPrefix(s[i:], ">="):
+		t.op, t.value = OpGreaterOrEqual, s[i+2:]
+	case s[i] == '<':
+		t.op, t.value = OpLess, s[i+1:]
+	default:
+		t.op, t.value = OpGreater, s[i+1:]
+	}
+
+	switch t.op {
+	case OpContains, OpExact:
+	case OpRegexp:
+		t.re, err = regexp.Compile(t.value)
+		if err != nil {
+			return t, err
+		}
+	default:
+		switch t.field.kind {
+		case kindNumber:
+			t.number, err = strconv.ParseInt(t.value, 10, 64)
+			if err != nil {
+				return t, fmt.Errorf("field %s is numeric, was: %s", name, t.value)
 			}
-		case OpExact:
-			filter.matcher = func(filterValue, fieldValue string) bool {
-				return fieldValue == filterValue
+		case kindTimestamp:
+			if len(t.value) == 0 || len(t.value) > 14 || strings.Trim(t.value, "0123456789") != "" {
+				return t, fmt.Errorf("field %s is a timestamp (yyyyMMddHHmmss or a prefix), was: %s", name, t.value)
 			}
-		case OpRegexp:
-			filter.matcher = func(filterValue, fieldValue string) bool {
-				return regexp.MustCompile(filterValue).MatchString(fieldValue)
+			// pad the timestamp so that e.g. sts<=2020 includes all of 2020
+			pad := "0"
+			if t.op == OpGreater || t.op == OpLessOrEqual {
+				pad = "9"
 			}
+			t.value += strings.Repeat(pad, 14-len(t.value))
 		}
-		filters = append(filters, filter)
 	}
+	return t, nil
+}
+
+type fieldKind int
 
-	return filters
+const (
+	kindString fieldKind = iota
+	kindNumber
+	kindTimestamp
+)
+
+// field is a field of a cdx record that can be filtered on.
+type field struct {
+	kind fieldKind
+	// get returns the value of the field and whether the field is present.
+	get func(c *schema.Cdx) (string, bool)
 }
 
-type filter struct {
-	field       string
-	filterValue string
-	invert      bool
-	matcher     func(filterValue, fieldValue string) bool
+// keyFields are fields derived from the ssurt of a cdx record.
+var keyFields = map[string]field{
+	"host": {kind: kindString, get: func(c *schema.Cdx) (string, bool) {
+		host, _, _ := surt.SplitSSURT(c.GetSsu())
+		return host, c.GetSsu() != ""
+	}},
+	"scheme": {kind: kindString, get: func(c *schema.Cdx) (string, bool) {
+		_, portSchemeUserInfo, _ := surt.SplitSSURT(c.GetSsu())
+		_, scheme, _ := surt.SplitPortSchemeUserInfo(portSchemeUserInfo)
+		return scheme, scheme != ""
+	}},
+	"port": {kind: kindNumber, get: func(c *schema.Cdx) (string, bool) {
+		_, portSchemeUserInfo, _ := surt.SplitSSURT(c.GetSsu())
+		port, _, _ := surt.SplitPortSchemeUserInfo(portSchemeUserInfo)
+		return port, port != ""
+	}},
+	"userinfo": {kind: kindString, get: func(c *schema.Cdx) (string, bool) {
+		_, portSchemeUserInfo, _ := surt.SplitSSURT(c.GetSsu())
+		_, _, userInfo := surt.SplitPortSchemeUserInfo(portSchemeUserInfo)
+		return userInfo, userInfo != ""
+	}},
+	"path": {kind: kindString, get: func(c *schema.Cdx) (string, bool) {
+		_, _, path := surt.SplitSSURT(c.GetSsu())
+		return path, c.GetSsu() != ""
+	}},
 }
 
+// filterField returns the field with name.
+func filterField(name string) (field, error) {
+	if f, ok := keyFields[name]; ok {
+		return f, nil
+	}
+	fd, err := CdxField(name)
+	if err != nil {
+		return field{}, err
+	}
+	f := field{
+		kind: kindString,
+		get: func(c *schema.Cdx) (string, bool) {
+			if !c.ProtoReflect().Has(fd) {
+				return "", false
+			}
+			return CdxFieldValue(c, fd), true
+		},
+	}
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		f.kind = kindNumber
+	case protoreflect.MessageKind:
+		f.kind = kindTimestamp
+	default:
+	}
+	return f, nil
+}
+
+// filter is a list of terms where at least one must match.
+type filter []term
+
 func (f filter) eval(c *schema.Cdx) bool {
+	for _, t := range f {
+		if t.eval(c) {
+			return true
+		}
+	}
+	return false
+}
+
+type term struct {
+	field  field
+	op     Op
+	value  string
+	invert bool
+	re     *regexp.Regexp
+	number int64
+}
+
+func (t term) eval(c *schema.Cdx) bool {
 	result := false
-	if fieldValue, found := f.findFieldValue(c); found {
-		result = f.matcher(f.filterValue, fieldValue)
-	} else if f.filterValue == "" {
+	if fieldValue, found := t.field.get(c); found {
+		result = t.match(fieldValue)
+	} else if t.value == "" && t.op <= OpRegexp {
 		result = true
 	}
-	if f.invert {
+	if t.invert {
 		return !result
-	} else {
-		return result
 	}
+	return result
 }
 
-func (f filter) findFieldValue(c *schema.Cdx) (fieldValue string, found bool) {
-	c.ProtoReflect().Range(func(descriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
-		if string(descriptor.Name()) == f.field {
-			found = true
-			fieldValue = value.String()
+func (t term) match(fieldValue string) bool {
+	switch t.op {
+	case OpContains:
+		return strings.Contains(fieldValue, t.value)
+	case OpExact:
+		return fieldValue == t.value
+	case OpRegexp:
+		return t.re.MatchString(fieldValue)
+	}
+	var cmp int
+	if t.field.kind == kindNumber {
+		n, err := strconv.ParseInt(fieldValue, 10, 64)
+		if err != nil {
 			return false
 		}
-		return true
-	})
-	return
+		switch {
+		case n < t.number:
+			cmp = -1
+		case n > t.number:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(fieldValue, t.value)
+	}
+	switch t.op {
+	case OpLess:
+		return cmp < 0
+	case OpLessOrEqual:
+		return cmp <= 0
+	case OpGreater:
+		return cmp > 0
+	case OpGreaterOrEqual:
+		return cmp >= 0
+	default:
+		return false
+	}
 }
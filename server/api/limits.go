@@ -0,0 +1,137 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+)
+
+// HeaderTruncated is the trailer holding the reason the results of a query were truncated.
+const HeaderTruncated = "Truncated"
+
+// Reasons the results of a query were truncated.
+const (
+	TruncatedTimeout    = "timeout"
+	TruncatedMaxKeys    = "max-keys"
+	TruncatedMaxValues  = "max-values"
+	TruncatedMaxResults = "max-results"
+)
+
+// DefaultEndpoint is the endpoint of the limits used by endpoints without limits of their own.
+const DefaultEndpoint = "default"
+
+// Limits limits the work of a query. A zero value is unlimited.
+type Limits struct {
+	// Timeout is the maximum duration of the query.
+	Timeout time.Duration
+	// MaxKeys is the maximum number of keys scanned.
+	MaxKeys int
+	// MaxValues is the maximum number of values decoded.
+	MaxValues int
+	// MaxResults is the maximum number of results returned.
+	MaxResults int
+}
+
+// QueryLimits are the limits of queries by endpoint.
+type QueryLimits map[string]Limits
+
+// Get returns the limits of endpoint, or the limits of DefaultEndpoint if endpoint has no limits.
+func (q QueryLimits) Get(endpoint string) Limits {
+	if l, ok := q[endpoint]; ok {
+		return l
+	}
+	return q[DefaultEndpoint]
+}
+
+// Apply applies the limits to req and returns a context that is canceled when the timeout expires.
+func (l Limits) Apply(ctx context.Context, req *SearchRequest) (context.Context, context.CancelFunc) {
+	req.limits = l
+	if l.MaxResults > 0 && (req.limit <= 0 || req.limit > l.MaxResults) {
+		req.limit = l.MaxResults
+		req.limited = true
+	}
+	return l.Context(ctx)
+}
+
+// Context returns a context that is canceled when the timeout of the limits expires.
+func (l Limits) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.Timeout > 0 {
+		return context.WithTimeout(ctx, l.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Truncation returns the reason the results of a search of req were truncated, or
+// empty if they were not. err is the error that ended the search and count is
+// the number of results.
+func (c *SearchRequest) Truncation(count int, err error) string {
+	if truncated := TruncatedBy(err); truncated != "" {
+		return truncated
+	}
+	if c.limited && count >= c.limit {
+		return TruncatedMaxResults
+	}
+	return ""
+}
+
+// TruncatedBy returns the reason results are truncated by err, or empty if err is not caused by a limit.
+func TruncatedBy(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return TruncatedTimeout
+	case errors.Is(err, index.MaxKeysExceededError):
+		return TruncatedMaxKeys
+	case errors.Is(err, index.MaxValuesExceededError):
+		return TruncatedMaxValues
+	}
+	return ""
+}
+
+func (c *SearchRequest) Budget() index.Budget {
+	if c.limits.MaxKeys <= 0 && c.limits.MaxValues <= 0 {
+		return nil
+	}
+	return &budget{maxKeys: c.limits.MaxKeys, maxValues: c.limits.MaxValues}
+}
+
+// budget counts the keys scanned and values decoded by a search.
+type budget struct {
+	keys      int
+	values    int
+	maxKeys   int
+	maxValues int
+}
+
+func (b *budget) ScanKey() error {
+	b.keys++
+	if b.maxKeys > 0 && b.keys > b.maxKeys {
+		return index.MaxKeysExceededError
+	}
+	return nil
+}
+
+func (b *budget) DecodeValue() error {
+	b.values++
+	if b.maxValues > 0 && b.values > b.maxValues {
+		return index.MaxValuesExceededError
+	}
+	return nil
+}
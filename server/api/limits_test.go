@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+)
+
+func TestQueryLimitsGet(t *testing.T) {
+	limits := QueryLimits{
+		DefaultEndpoint: {Timeout: time.Second},
+		"cdx":           {MaxKeys: 10},
+	}
+	if got := limits.Get("cdx"); got != (Limits{MaxKeys: 10}) {
+		t.Errorf("got %+v, want limits of cdx", got)
+	}
+	if got := limits.Get("debug"); got != (Limits{Timeout: time.Second}) {
+		t.Errorf("got %+v, want default limits", got)
+	}
+	if got := QueryLimits(nil).Get("cdx"); got != (Limits{}) {
+		t.Errorf("got %+v, want no limits", got)
+	}
+}
+
+func TestLimitsApply(t *testing.T) {
+	tests := []struct {
+		limit      int
+		maxResults int
+		count      int
+		wantLimit  int
+		want       string
+	}{
+		{limit: 0, maxResults: 0, count: 100, wantLimit: 0, want: ""},
+		{limit: 0, maxResults: 5, count: 5, wantLimit: 5, want: TruncatedMaxResults},
+		{limit: 10, maxResults: 5, count: 4, wantLimit: 5, want: ""},
+		{limit: 3, maxResults: 5, count: 3, wantLimit: 3, want: ""},
+		{limit: -1, maxResults: 5, count: 5, wantLimit: 5, want: TruncatedMaxResults},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d/%d", test.limit, test.maxResults), func(t *testing.T) {
+			req := &SearchRequest{limit: test.limit}
+			_, cancel := Limits{MaxResults: test.maxResults}.Apply(context.Background(), req)
+			defer cancel()
+			if req.Limit() != test.wantLimit {
+				t.Errorf("got limit %d, want %d", req.Limit(), test.wantLimit)
+			}
+			if got := req.Truncation(test.count, nil); got != test.want {
+				t.Errorf("got truncation %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: ""},
+		{err: errors.New("failed"), want: ""},
+		{err: context.Canceled, want: ""},
+		{err: context.DeadlineExceeded, want: TruncatedTimeout},
+		{err: index.MaxKeysExceededError, want: TruncatedMaxKeys},
+		{err: fmt.Errorf("search: %w", index.MaxValuesExceededError), want: TruncatedMaxValues},
+	}
+	for _, test := range tests {
+		req := new(SearchRequest)
+		if got := req.Truncation(0, test.err); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.err, got, test.want)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	if (&SearchRequest{}).Budget() != nil {
+		t.Error("expected no budget without limits")
+	}
+	req := new(SearchRequest)
+	_, cancel := Limits{MaxKeys: 2, MaxValues: 1}.Apply(context.Background(), req)
+	defer cancel()
+
+	budget := req.Budget()
+	for i := 0; i < 2; i++ {
+		if err := budget.ScanKey(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := budget.ScanKey(); !errors.Is(err, index.MaxKeysExceededError) {
+		t.Errorf("got %v, want %v", err, index.MaxKeysExceededError)
+	}
+	if err := budget.DecodeValue(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := budget.DecodeValue(); !errors.Is(err, index.MaxValuesExceededError) {
+		t.Errorf("got %v, want %v", err, index.MaxValuesExceededError)
+	}
+	// every search has its own budget
+	if err := req.Budget().ScanKey(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
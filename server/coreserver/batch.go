@@ -0,0 +1,230 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package coreserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultBatchConcurrency is the number of concurrent lookups of a batch when not configured.
+const DefaultBatchConcurrency = 8
+
+// maxBatchLineSize is the maximum size of a line of a batch.
+const maxBatchLineSize = 1024 * 1024
+
+// stringList is a list of strings that can be unmarshalled from a string or an array of strings.
+type stringList []string
+
+func (s *stringList) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*s = stringList{str}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// batchItem is a lookup of a batch.
+type batchItem struct {
+	Url       string     `json:"url"`
+	Closest   string     `json:"closest,omitempty"`
+	MatchType string     `json:"matchType,omitempty"`
+	Filter    stringList `json:"filter,omitempty"`
+	// Limit is the maximum number of results of the lookup, defaults to 1.
+	Limit int `json:"limit,omitempty"`
+}
+
+// batchResult is the result group of the lookup of the batch item with index.
+type batchResult struct {
+	Index   int               `json:"index"`
+	Results []json.RawMessage `json:"results"`
+	Error   string            `json:"error,omitempty"`
+	// Truncated is the reason the results were truncated, if they were.
+	Truncated string `json:"truncated,omitempty"`
+	// status is the status code of the error, if any.
+	status int
+}
+
+// values returns the search parameters of the batch item.
+func (item batchItem) values() url.Values {
+	values := url.Values{}
+	values.Set(api.ParamUrl, item.Url)
+	if item.MatchType != "" {
+		values.Set(api.ParamMatchType, item.MatchType)
+	}
+	if item.Closest != "" {
+		values.Set(api.ParamClosest, item.Closest)
+		values.Set(api.ParamSort, api.SortClosest)
+	}
+	for _, filter := range item.Filter {
+		values.Add(api.ParamFilter, filter)
+	}
+	limit := item.Limit
+	if limit <= 0 {
+		limit = 1
+	}
+	values.Set(api.ParamLimit, strconv.Itoa(limit))
+	return values
+}
+
+// lookup searches the batch item at position n.
+func (h Handler) lookup(ctx context.Context, n int, item batchItem) batchResult {
+	result := batchResult{Index: n, Results: []json.RawMessage{}}
+
+	req, err := api.Parse(item.values())
+	if err != nil {
+		result.Error = err.Error()
+		result.status = http.StatusBadRequest
+		return result
+	}
+	ctx, cancel := h.Limits.Get("batch").Apply(ctx, req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		result.Error = err.Error()
+		result.status = http.StatusInternalServerError
+		return result
+	}
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("failed result")
+			}
+			if searchErr == nil {
+				searchErr = err
+			}
+			// the first error of the lookup not caused by the limits is reported
+			if result.Error == "" && api.TruncatedBy(err) == "" {
+				result.Error = err.Error()
+				result.status = http.StatusInternalServerError
+			}
+			continue
+		}
+		v, err := protojson.Marshal(res.GetCdx())
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to marshal result")
+			continue
+		}
+		result.Results = append(result.Results, v)
+	}
+	result.Truncated = req.Truncation(len(result.Results), searchErr)
+	return result
+}
+
+// batch looks up the newline delimited json items of the request body concurrently and
+// writes a newline delimited json result group for each item as soon as it is ready.
+func (h Handler) batch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	concurrency := h.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	// the request body is read while results are written
+	rc := http.NewResponseController(w)
+	_ = rc.EnableFullDuplex()
+
+	results := make(chan batchResult)
+	send := func(result batchResult) {
+		select {
+		case <-ctx.Done():
+		case results <- result:
+		}
+	}
+
+	go func() {
+		defer close(results)
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		sem := make(chan struct{}, concurrency)
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+		i := 0
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			n := i
+			i++
+			var item batchItem
+			if err := json.Unmarshal(line, &item); err != nil {
+				send(batchResult{Index: n, Results: []json.RawMessage{}, Error: err.Error(), status: http.StatusBadRequest})
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				send(h.lookup(ctx, n, item))
+			}()
+		}
+		if err := scanner.Err(); err != nil {
+			send(batchResult{Index: i, Results: []json.RawMessage{}, Error: err.Error(), status: http.StatusBadRequest})
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	// errors are written in-band in the result of each item
+	stream := newStreamStatus(w, false)
+	defer stream.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for result := range results {
+		if result.status != 0 {
+			stream.setStatus(result.status)
+		}
+		if err := enc.Encode(result); err != nil {
+			log.Warn().Err(err).Msg("failed to write result")
+			cancel()
+			continue
+		}
+		_ = rc.Flush()
+		count++
+	}
+	log.Debug().Msgf("Looked up %d batch items", count)
+}
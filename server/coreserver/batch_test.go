@@ -0,0 +1,90 @@
+package coreserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBatch(t *testing.T) {
+	ts := timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC))
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{
+			{Uri: "http://example.com/", Sts: ts, Hsc: 200},
+			{Uri: "http://example.com/", Sts: ts, Hsc: 404},
+			{Uri: "http://example.org/", Sts: ts, Hsc: 200},
+		},
+		BatchConcurrency: 2,
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+
+	body := strings.Join([]string{
+		`{"url": "http://example.com/", "closest": "20200401"}`,
+		`{"url": "http://example.com/", "filter": "hsc:404"}`,
+		``,
+		`{"url": "http://example.com/", "limit": 5}`,
+		`{"url": "http://example.net/"}`,
+		`not json`,
+		`{"url": "http://example.org/", "matchType": "nope"}`,
+	}, "\n")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("POST", "http://localhost/cdx/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	type result struct {
+		results int
+		err     string
+	}
+	got := map[int]result{}
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var r batchResult
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		got[r.Index] = result{results: len(r.Results), err: r.Error}
+	}
+
+	want := []struct {
+		results int
+		err     bool
+	}{
+		{results: 1},
+		{results: 1},
+		{results: 2},
+		{results: 0},
+		{err: true},
+		{err: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d result groups, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		r, ok := got[i]
+		if !ok {
+			t.Errorf("missing result group %d", i)
+			continue
+		}
+		if (r.err != "") != w.err {
+			t.Errorf("%d: got error %q, want error %v", i, r.err, w.err)
+		}
+		if r.results != w.results {
+			t.Errorf("%d: got %d results, want %d", i, r.results, w.results)
+		}
+	}
+	// the failed items are bad requests
+	if got := rec.Result().Trailer.Get(api.HeaderStreamStatus); got != "400" {
+		t.Errorf("got %s trailer %q, want %q", api.HeaderStreamStatus, got, "400")
+	}
+}
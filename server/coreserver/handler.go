@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,11 +29,14 @@ import (
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarc"
 	"github.com/nlnwa/gowarcserver/index"
 	"github.com/nlnwa/gowarcserver/internal/keyvalue"
 	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
 	"github.com/nlnwa/gowarcserver/server/api"
 	"github.com/nlnwa/gowarcserver/server/handlers"
+	"github.com/nlnwa/gowarcserver/server/mementoserver"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -45,8 +49,16 @@ type Handler struct {
 	FileAPI            index.FileAPI
 	IdAPI              index.IdAPI
 	ReportAPI          index.ReportAPI
+	HistogramAPI       index.HistogramAPI
+	UrlAPI             keyvalue.UrlAPI
 	StorageRefResolver loader.StorageRefResolver
 	WarcLoader         loader.WarcLoader
+	// BatchConcurrency is the number of concurrent lookups of a batch.
+	BatchConcurrency int
+	// Limits are the query limits by endpoint.
+	Limits api.QueryLimits
+	// WebPath is the path of the endpoint replaying the mementos of the link output.
+	WebPath string
 }
 
 func (h Handler) debug(w http.ResponseWriter, r *http.Request) {
@@ -60,14 +72,14 @@ func (h Handler) debug(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	ctx, cancel := h.Limits.Get("debug").Apply(r.Context(), coreAPI)
+	defer cancel()
+
 	req := keyvalue.DebugRequest{
 		Key:     r.URL.Query().Get("key"),
 		Request: coreAPI,
 	}
 
-	ctx, cancel := context.WithCancel(r.Context())
-	defer cancel()
-
 	response := make(chan keyvalue.CdxResponse)
 
 	if err := h.DebugAPI.Debug(ctx, req, response); err != nil {
@@ -81,8 +93,22 @@ func (h Handler) debug(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
+	// the reason of truncation is only known when all results are written
+	w.Header().Set("Trailer", api.HeaderTruncated)
+	stream := newStreamStatus(w, true)
+	defer stream.Close()
+
+	var searchErr error
 	enc := json.NewEncoder(w)
 	for res := range response {
+		if res.GetError() != nil {
+			searchErr = res.GetError()
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
+			continue
+		}
 		err = enc.Encode(res)
 		if err != nil {
 			log.Warn().Err(err).Msg("failed to marshal result")
@@ -90,6 +116,9 @@ func (h Handler) debug(w http.ResponseWriter, r *http.Request) {
 		}
 		count++
 	}
+	if truncated := coreAPI.Truncation(count, searchErr); truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
 }
 
 func (h Handler) search(w http.ResponseWriter, r *http.Request) {
@@ -105,35 +134,67 @@ func (h Handler) search(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Str("request", fmt.Sprintf("%+v", coreAPI)).Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
-	ctx, cancel := context.WithCancel(r.Context())
+	cdxWriter, err := newCdxWriter(w, coreAPI.Output(), coreAPI.Fields(), mementoserver.BaseUrl(r), h.WebPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.Limits.Get("cdx").Apply(r.Context(), coreAPI)
 	defer cancel()
 
 	response := make(chan index.CdxResponse)
 
 	if err = h.CdxAPI.Search(ctx, coreAPI, response); err != nil {
+		if errors.Is(err, index.InvalidResumeKeyError) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Error().Err(err).Msgf("Search failed: %+v", coreAPI)
 		return
 	}
 
+	// the resume key and the reason of truncation are only known when all results are written
+	w.Header().Add("Trailer", api.HeaderResumeKey)
+	w.Header().Add("Trailer", api.HeaderTruncated)
+	// errors are written in-band when the output is newline delimited json
+	_, ndjson := cdxWriter.(*jsonWriter)
+	stream := newStreamStatus(w, ndjson)
+	defer stream.Close()
+
+	var last *schema.Cdx
+	var searchErr error
 	for res := range response {
 		if res.GetError() != nil {
 			log.Warn().Err(res.GetError()).Msg("failed result")
+			searchErr = res.GetError()
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
 			continue
 		}
-		v, err := protojson.Marshal(res.GetCdx())
-		if err != nil {
-			log.Warn().Err(err).Msg("failed to marshal result")
-			continue
-		}
-		_, err = io.Copy(w, bytes.NewReader(v))
+		err = cdxWriter.Write(res.GetCdx())
 		if err != nil {
 			log.Warn().Err(err).Msg("failed to write result")
 			return
 		}
-		_, _ = w.Write(lf)
+		last = res.GetCdx()
 		count++
 	}
+	if err := cdxWriter.Flush(); err != nil {
+		log.Warn().Err(err).Msg("failed to write result")
+		return
+	}
+	truncated := coreAPI.Truncation(count, searchErr)
+	if truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+	// there may be more results if the limit was reached or the results were truncated
+	if last != nil && (truncated != "" || coreAPI.Limit() > 0 && count >= coreAPI.Limit()) {
+		w.Header().Set(api.HeaderResumeKey, api.EncodeResumeKey(keyvalue.CdxKeyOf(last).String()))
+	}
 }
 
 type storageRef struct {
@@ -164,14 +225,25 @@ func (h Handler) listIds(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
+	stream := newStreamStatus(w, true)
+	defer stream.Close()
+
 	for res := range response {
 		if res.GetError() != nil {
 			log.Warn().Err(res.GetError()).Msg("failed result")
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
 			continue
 		}
-		filename, offset, err := parseStorageRef(res.GetValue())
+		filename, offset, err := loader.ParseStorageRef(res.GetValue())
 		if err != nil {
 			log.Warn().Err(err).Msgf("failed to parse storage ref: %s", res.GetValue())
+			if err := stream.Error(fmt.Errorf("failed to parse storage ref of %s: %w", res.GetId(), err)); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
 			continue
 		}
 		ref := &storageRef{
@@ -235,9 +307,16 @@ func (h Handler) listFiles(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
+	stream := newStreamStatus(w, true)
+	defer stream.Close()
+
 	for res := range responses {
 		if res.GetError() != nil {
 			log.Warn().Err(res.GetError()).Msg("failed result")
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
 			continue
 		}
 		v, err := protojson.Marshal(res.GetFileInfo())
@@ -257,7 +336,8 @@ func (h Handler) listFiles(w http.ResponseWriter, r *http.Request) {
 
 func (h Handler) getFileInfoByFilename(w http.ResponseWriter, r *http.Request) {
 	params := httprouter.ParamsFromContext(r.Context())
-	filename := params.ByName("filename")
+	// filename is a catch-all parameter because the file identity may be a path
+	filename := strings.TrimPrefix(params.ByName("filename"), "/")
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
@@ -298,33 +378,14 @@ func (h Handler) loadRecordByUrn(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	_, err = handlers.RenderRecord(w, record)
+	// records are immutable so the record id is a strong validator of ranges
+	w.Header().Set("Etag", strconv.Quote(record.WarcHeader().GetId(gowarc.WarcRecordID)))
+	_, err = handlers.ServeRecord(w, r, record)
 	if err != nil {
 		log.Warn().Err(err).Msgf("Failed to write record '%s': %v", warcId, record)
 	}
 }
 
-func parseStorageRef(ref string) (filename string, offset int64, err error) {
-	n := strings.IndexRune(ref, ':')
-	if n == -1 {
-		err = fmt.Errorf("invalid storage ref, missing scheme delimiter ':'")
-		return
-	}
-	ref = ref[n+1:]
-	n = strings.IndexRune(ref, '#')
-	if n == -1 {
-		err = fmt.Errorf("invalid storage ref, missing offset delimiter '#'")
-		return
-	}
-	filename = ref[:n]
-	offset, err = strconv.ParseInt(ref[n+1:], 10, 64)
-	if err != nil {
-		err = fmt.Errorf("invalid storage ref, invalid offset: %w", err)
-		return
-	}
-	return
-}
-
 func (h Handler) createReport(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -415,9 +476,16 @@ func (h Handler) listReports(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
+	stream := newStreamStatus(w, true)
+	defer stream.Close()
+
 	for res := range responses {
 		if res.GetError() != nil {
 			log.Warn().Err(res.GetError()).Msg("failed report result")
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
 			continue
 		}
 		v, err := protojson.Marshal(res.GetReport())
@@ -0,0 +1,53 @@
+package coreserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSearchTruncated(t *testing.T) {
+	ts := timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC))
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{
+			{Uri: "http://example.com/", Ssu: "com,example,//:http:/", Sts: ts, Hsc: 200},
+			{Uri: "http://example.com/", Ssu: "com,example,//:http:/", Sts: ts, Hsc: 404},
+		},
+		Limits: api.QueryLimits{"cdx": {MaxResults: 1}},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+
+	tests := []struct {
+		query     string
+		truncated string
+	}{
+		{query: "url=http://example.com/", truncated: api.TruncatedMaxResults},
+		// the limit of the request is not lowered by the limits
+		{query: "url=http://example.com/&limit=1", truncated: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/cdx?"+test.query, nil))
+			res := rec.Result()
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusOK)
+			}
+			if got := res.Trailer.Get(api.HeaderTruncated); got != test.truncated {
+				t.Errorf("got %s trailer %q, want %q", api.HeaderTruncated, got, test.truncated)
+			}
+			if got := res.Trailer.Get(api.HeaderStreamStatus); got != "200" {
+				t.Errorf("got %s trailer %q, want %q", api.HeaderStreamStatus, got, "200")
+			}
+			if res.Trailer.Get(api.HeaderResumeKey) == "" {
+				t.Errorf("expected %s trailer", api.HeaderResumeKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package coreserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/rs/zerolog/log"
+)
+
+// granularities maps the granularity of a histogram to a number of timestamp digits.
+var granularities = map[string]int{
+	"year":   4,
+	"month":  6,
+	"day":    8,
+	"hour":   10,
+	"minute": 12,
+	"second": 14,
+}
+
+// histogram writes the number of captures matching the request by timestamp as a
+// json object, e.g. {"2020": 10, "2021": 2} for granularity year.
+func (h Handler) histogram(w http.ResponseWriter, r *http.Request) {
+	if h.HistogramAPI == nil {
+		http.Error(w, "Histogram API not implemented", http.StatusNotImplemented)
+		return
+	}
+	coreAPI, err := api.Parse(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = "year"
+	}
+	n, ok := granularities[granularity]
+	if !ok {
+		http.Error(w, fmt.Sprintf("granularity must be one of year, month, day, hour, minute or second, was: %s", granularity), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.Limits.Get("histogram").Apply(r.Context(), coreAPI)
+	defer cancel()
+
+	start := time.Now()
+	histogram, err := h.HistogramAPI.Histogram(ctx, coreAPI, n)
+	// a histogram that exceeds the limits is truncated
+	truncated := coreAPI.Truncation(0, err)
+	if err != nil && truncated == "" {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msgf("Histogram failed: %+v", coreAPI)
+		return
+	}
+	log.Debug().Msgf("Found %d buckets in %s", len(histogram), time.Since(start))
+
+	if truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(histogram); err != nil {
+		log.Warn().Err(err).Msg("failed to write histogram")
+	}
+}
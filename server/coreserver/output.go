@@ -0,0 +1,303 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package coreserver
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/server/mementoserver"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// cdxWriter writes cdx records in an output format.
+type cdxWriter interface {
+	// Write writes a cdx record.
+	Write(cdx *schema.Cdx) error
+	// Flush writes any buffered data.
+	Flush() error
+}
+
+// newCdxWriter returns a cdxWriter for output, projected to fields. The mementos of the
+// link output are replayed at webPath of baseUrl.
+//
+// The content type of the output format is set on w.
+func newCdxWriter(w http.ResponseWriter, output string, fields []string, baseUrl string, webPath string) (cdxWriter, error) {
+	switch output {
+	case api.OutputText, api.OutputCsv:
+		columns := cdx11Columns
+		if len(fields) > 0 {
+			var err error
+			if columns, err = fieldColumns(fields); err != nil {
+				return nil, err
+			}
+		}
+		if output == api.OutputText {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			return &textWriter{w: w, columns: columns}, nil
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		return &csvWriter{w: csv.NewWriter(w), columns: columns}, nil
+	case api.OutputLink:
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("%s is not supported with %s=%s", api.ParamFields, api.ParamOutput, output)
+		}
+		w.Header().Set("Content-Type", "application/link-format")
+		return &linkWriter{w: w, baseUrl: baseUrl, webPath: webPath}, nil
+	case api.OutputPb:
+		projection, err := fieldDescriptors(fields)
+		if err != nil {
+			return nil, err
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf; delimited=true")
+		return &pbWriter{w: w, fields: projection}, nil
+	case "", api.OutputJson, api.OutputCdxj:
+		projection, err := fieldDescriptors(fields)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonWriter{w: w, fields: projection}, nil
+	default:
+		return nil, fmt.Errorf("%s=%s is not supported", api.ParamOutput, output)
+	}
+}
+
+// fieldDescriptors returns the descriptors of the cdx fields named by fields.
+func fieldDescriptors(fields []string) ([]protoreflect.FieldDescriptor, error) {
+	var fds []protoreflect.FieldDescriptor
+	for _, name := range fields {
+		fd, err := api.CdxField(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", api.ParamFields, err)
+		}
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
+
+// project returns a copy of cdx with only fields set, or cdx if fields is empty.
+func project(cdx *schema.Cdx, fields []protoreflect.FieldDescriptor) *schema.Cdx {
+	if len(fields) == 0 {
+		return cdx
+	}
+	src := cdx.ProtoReflect()
+	dst := new(schema.Cdx)
+	m := dst.ProtoReflect()
+	for _, fd := range fields {
+		if src.Has(fd) {
+			m.Set(fd, src.Get(fd))
+		}
+	}
+	return dst
+}
+
+type column struct {
+	name string
+	get  func(cdx *schema.Cdx) string
+}
+
+// cdx11Columns are the columns of the classic CDX11 format.
+var cdx11Columns = []column{
+	{"urlkey", api.UrlKey},
+	{"timestamp", func(cdx *schema.Cdx) string { return timestamp.TimeTo14(cdx.GetSts().AsTime()) }},
+	{"original", (*schema.Cdx).GetUri},
+	{"mimetype", func(cdx *schema.Cdx) string { return api.MediaType(cdx.GetMct()) }},
+	{"statuscode", func(cdx *schema.Cdx) string {
+		if cdx.GetHsc() == 0 {
+			return ""
+		}
+		return strconv.Itoa(int(cdx.GetHsc()))
+	}},
+	{"digest", (*schema.Cdx).GetDig},
+	{"redirect", func(*schema.Cdx) string { return "" }},
+	{"robotflags", func(*schema.Cdx) string { return "" }},
+	{"length", func(cdx *schema.Cdx) string { return strconv.FormatInt(cdx.GetRle(), 10) }},
+	{"offset", func(cdx *schema.Cdx) string {
+		_, offset, err := loader.ParseStorageRef(cdx.GetRef())
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatInt(offset, 10)
+	}},
+	{"filename", func(cdx *schema.Cdx) string {
+		filename, _, err := loader.ParseStorageRef(cdx.GetRef())
+		if err != nil {
+			return ""
+		}
+		return filename
+	}},
+}
+
+// fieldColumns returns columns of the cdx fields named by fields.
+func fieldColumns(fields []string) ([]column, error) {
+	fds, err := fieldDescriptors(fields)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]column, len(fds))
+	for i, fd := range fds {
+		fd := fd
+		columns[i] = column{
+			name: fields[i],
+			get: func(cdx *schema.Cdx) string {
+				return api.CdxFieldValue(cdx, fd)
+			},
+		}
+	}
+	return columns, nil
+}
+
+// jsonWriter writes cdx records as newline delimited protojson.
+type jsonWriter struct {
+	w      io.Writer
+	fields []protoreflect.FieldDescriptor
+}
+
+func (j *jsonWriter) Write(cdx *schema.Cdx) error {
+	v, err := protojson.Marshal(project(cdx, j.fields))
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(append(v, lf...))
+	return err
+}
+
+func (j *jsonWriter) Flush() error {
+	return nil
+}
+
+// pbWriter writes cdx records as length delimited protobuf messages.
+type pbWriter struct {
+	w      io.Writer
+	fields []protoreflect.FieldDescriptor
+}
+
+func (p *pbWriter) Write(cdx *schema.Cdx) error {
+	_, err := protodelim.MarshalTo(p.w, project(cdx, p.fields))
+	return err
+}
+
+func (p *pbWriter) Flush() error {
+	return nil
+}
+
+// textWriter writes cdx records as space separated lines where empty values are written as "-"
+// and whitespace in values is percent-encoded.
+type textWriter struct {
+	w       io.Writer
+	columns []column
+}
+
+func (t *textWriter) Write(cdx *schema.Cdx) error {
+	var sb strings.Builder
+	for i, c := range t.columns {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		v := c.get(cdx)
+		if v == "" {
+			v = "-"
+		}
+		sb.WriteString(api.TextValue(v))
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(t.w, sb.String())
+	return err
+}
+
+func (t *textWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes cdx records as csv with a header row.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []column
+	header  bool
+}
+
+func (c *csvWriter) Write(cdx *schema.Cdx) error {
+	if !c.header {
+		if err := c.w.Write(c.record(func(col column) string { return col.name })); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	if err := c.w.Write(c.record(func(col column) string { return col.get(cdx) })); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) record(value func(column) string) []string {
+	record := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		record[i] = value(col)
+	}
+	return record
+}
+
+func (c *csvWriter) Flush() error {
+	if !c.header {
+		if err := c.w.Write(c.record(func(col column) string { return col.name })); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// linkWriter writes cdx records as mementos in the link format (RFC 6690), where the
+// target of each link is the URI-M of the memento (RFC 7089).
+type linkWriter struct {
+	w       io.Writer
+	baseUrl string
+	webPath string
+	count   int
+}
+
+func (l *linkWriter) Write(cdx *schema.Cdx) error {
+	sep := ""
+	if l.count > 0 {
+		sep = ",\n"
+	}
+	l.count++
+	t := cdx.GetSts().AsTime()
+	uri := mementoserver.MementoUrl(l.baseUrl, l.webPath, t, cdx.GetUri())
+	_, err := fmt.Fprint(l.w, sep, mementoserver.Link(uri, "rel", "memento", "datetime", mementoserver.Datetime(t)))
+	return err
+}
+
+func (l *linkWriter) Flush() error {
+	if l.count == 0 {
+		return nil
+	}
+	_, err := l.w.Write(lf)
+	return err
+}
@@ -0,0 +1,153 @@
+package coreserver
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/schema"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCdxWriter(t *testing.T) {
+	cdxs := []*schema.Cdx{
+		{
+			Uri: "http://www.example.com/",
+			Ssu: "com,example,www,//:http:/",
+			Sts: timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+			Mct: "text/html; charset=utf-8",
+			Hsc: 200,
+			Dig: "sha1:ABC",
+			Rle: 1234,
+			Ref: "warcfile:dir/file.warc.gz#42",
+		},
+		{
+			Uri: "dns:www.example.com",
+			Ssu: "com,example,www,//dns:",
+			Sts: timestamppb.New(time.Date(2020, time.April, 2, 22, 22, 0, 0, time.UTC)),
+			Rle: 100,
+			Ref: "warcfile:file.warc.gz#0",
+		},
+	}
+
+	tests := []struct {
+		output string
+		fields []string
+		want   string
+		err    bool
+	}{
+		{
+			output: "text",
+			want: "com,example)/ 20200401222200 http://www.example.com/ text/html 200 sha1:ABC - - 1234 42 dir/file.warc.gz\n" +
+				")www.example.com 20200402222200 dns:www.example.com - - - - - 100 0 file.warc.gz\n",
+		},
+		{
+			output: "text",
+			fields: []string{"uri", "sts", "hsc"},
+			want:   "http://www.example.com/ 20200401222200 200\ndns:www.example.com 20200402222200 0\n",
+		},
+		{
+			// values of fields are escaped
+			output: "text",
+			fields: []string{"uri", "mct"},
+			want:   "http://www.example.com/ text/html;%20charset=utf-8\ndns:www.example.com -\n",
+		},
+		{
+			output: "csv",
+			fields: []string{"uri", "mct"},
+			want:   "uri,mct\nhttp://www.example.com/,text/html; charset=utf-8\ndns:www.example.com,\n",
+		},
+		{
+			output: "link",
+			want: "<http://localhost/warcserver/web/20200401222200id_/http://www.example.com/>; rel=\"memento\"; datetime=\"Wed, 01 Apr 2020 22:22:00 GMT\",\n" +
+				"<http://localhost/warcserver/web/20200402222200id_/dns:www.example.com>; rel=\"memento\"; datetime=\"Thu, 02 Apr 2020 22:22:00 GMT\"\n",
+		},
+		{
+			output: "json",
+			fields: []string{"uri", "hsc"},
+			want:   "{\"uri\":\"http://www.example.com/\",\"hsc\":200}\n{\"uri\":\"dns:www.example.com\"}\n",
+		},
+		{
+			output: "json",
+			fields: []string{"nope"},
+			err:    true,
+		},
+		{
+			output: "link",
+			fields: []string{"uri"},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.output, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			w, err := newCdxWriter(rec, test.output, test.fields, "http://localhost", "/warcserver/web")
+			if test.err {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, cdx := range cdxs {
+				if err := w.Write(cdx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			// protojson output is not stable so spaces are removed before comparing
+			got := rec.Body.String()
+			if test.output == "json" {
+				got = removeSpaces(got)
+			}
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPbCdxWriter(t *testing.T) {
+	cdx := &schema.Cdx{Uri: "http://www.example.com/", Hsc: 200, Mct: "text/html"}
+
+	rec := httptest.NewRecorder()
+	w, err := newCdxWriter(rec, "pb", []string{"uri", "hsc"}, "http://localhost", "/warcserver/web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.Write(cdx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := &schema.Cdx{Uri: "http://www.example.com/", Hsc: 200}
+	r := bufio.NewReader(rec.Body)
+	for i := 0; i < 2; i++ {
+		got := new(schema.Cdx)
+		if err := protodelim.UnmarshalFrom(r, got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func removeSpaces(s string) string {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			b = append(b, s[i])
+		}
+	}
+	return string(b)
+}
@@ -26,8 +26,12 @@ func Register(h Handler, r *httprouter.Router, mw func(http.Handler) http.Handle
 	r.Handler("GET", pathPrefix+"/id", mw(http.HandlerFunc(h.listIds)))
 	r.Handler("GET", pathPrefix+"/id/:urn", mw(http.HandlerFunc(h.getStorageRefByURN)))
 	r.Handler("GET", pathPrefix+"/file", mw(http.HandlerFunc(h.listFiles)))
-	r.Handler("GET", pathPrefix+"/file/:filename", mw(http.HandlerFunc(h.getFileInfoByFilename)))
+	r.Handler("GET", pathPrefix+"/file/*filename", mw(http.HandlerFunc(h.getFileInfoByFilename)))
 	r.Handler("GET", pathPrefix+"/cdx", mw(http.HandlerFunc(h.search)))
+	r.Handler("POST", pathPrefix+"/cdx/batch", mw(http.HandlerFunc(h.batch)))
+	r.Handler("GET", pathPrefix+"/histogram", mw(http.HandlerFunc(h.histogram)))
+	r.Handler("GET", pathPrefix+"/hosts", mw(http.HandlerFunc(h.listHosts)))
+	r.Handler("GET", pathPrefix+"/urls", mw(http.HandlerFunc(h.listUrls)))
 	r.Handler("GET", pathPrefix+"/record/:urn", mw(http.HandlerFunc(h.loadRecordByUrn)))
 
 	// Debug handler
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package coreserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nlnwa/gowarcserver/server/api"
+)
+
+// errorLine is the line of a newline delimited json stream reporting an error, e.g.
+//
+//	{"error":{"status":500,"message":"failed to unmarshal value"}}
+type errorLine struct {
+	Error errorObject `json:"error"`
+}
+
+type errorObject struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// streamStatus reports the errors of a streamed response.
+//
+// Errors are written in-band as error lines if the stream is newline delimited
+// json, and the status of the stream is written to the Stream-Status trailer.
+// Results truncated by the limits of the request are not errors of the stream,
+// but are signalled by the Truncated trailer.
+type streamStatus struct {
+	w      http.ResponseWriter
+	inband bool
+	status int
+}
+
+// newStreamStatus declares the Stream-Status trailer and must be called before the first write to w.
+func newStreamStatus(w http.ResponseWriter, inband bool) *streamStatus {
+	w.Header().Add("Trailer", api.HeaderStreamStatus)
+	return &streamStatus{w: w, inband: inband, status: http.StatusOK}
+}
+
+// Error records err and writes it as an error line if errors are reported in-band,
+// unless err is caused by the limits of the request.
+func (s *streamStatus) Error(err error) error {
+	if api.TruncatedBy(err) != "" {
+		return nil
+	}
+	status := http.StatusInternalServerError
+	s.setStatus(status)
+	if !s.inband {
+		return nil
+	}
+	b, err := json.Marshal(errorLine{Error: errorObject{Status: status, Message: err.Error()}})
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(b, lf...))
+	return err
+}
+
+// setStatus sets the status of the stream unless it is already set by an earlier error.
+func (s *streamStatus) setStatus(status int) {
+	if s.status == http.StatusOK {
+		s.status = status
+	}
+}
+
+// Close writes the status of the stream to the trailer.
+func (s *streamStatus) Close() {
+	s.w.Header().Set(api.HeaderStreamStatus, strconv.Itoa(s.status))
+}
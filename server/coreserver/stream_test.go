@@ -0,0 +1,92 @@
+package coreserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newFailingCdxAPI returns a CdxAPI that responds with a cdx record followed by err.
+func newFailingCdxAPI(err error) index.CdxAPI {
+	ts := timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC))
+	return indextest.FailingCdxAPI{
+		CdxAPI: indextest.CdxAPI{{Uri: "http://example.com/", Ssu: "com,example,//:http:/", Sts: ts}},
+		After:  1,
+		Err:    err,
+	}
+}
+
+func TestSearchStreamStatus(t *testing.T) {
+	tests := []struct {
+		err       error
+		output    string
+		wantLines int
+		wantError *errorObject
+		want      string
+	}{
+		{
+			err:       errors.New("failed to unmarshal value"),
+			wantLines: 2,
+			wantError: &errorObject{Status: http.StatusInternalServerError, Message: "failed to unmarshal value"},
+			want:      "500",
+		},
+		{
+			// results truncated by the limits are only signalled by the Truncated trailer
+			err:       index.MaxKeysExceededError,
+			output:    api.OutputJson,
+			wantLines: 1,
+			want:      "200",
+		},
+		{
+			// errors are not written in-band when the output is not newline delimited json
+			err:       errors.New("failed to unmarshal value"),
+			output:    api.OutputText,
+			wantLines: 1,
+			want:      "500",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.want+test.output, func(t *testing.T) {
+			router := httprouter.New()
+			Register(Handler{CdxAPI: newFailingCdxAPI(test.err)}, router, func(h http.Handler) http.Handler { return h }, "")
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/cdx?url=http://example.com/&output="+test.output, nil))
+			res := rec.Result()
+
+			var lines []string
+			scanner := bufio.NewScanner(res.Body)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if len(lines) != test.wantLines {
+				t.Fatalf("got %d lines, want %d: %v", len(lines), test.wantLines, lines)
+			}
+			if test.wantError != nil {
+				var line errorLine
+				if err := json.Unmarshal([]byte(lines[len(lines)-1]), &line); err != nil {
+					t.Fatal(err)
+				}
+				if line.Error != *test.wantError {
+					t.Errorf("got error line %+v, want %+v", line.Error, *test.wantError)
+				}
+			} else if strings.Contains(lines[len(lines)-1], `"error"`) {
+				t.Errorf("unexpected error line: %s", lines[len(lines)-1])
+			}
+			if got := res.Trailer.Get(api.HeaderStreamStatus); got != test.want {
+				t.Errorf("got %s trailer %q, want %q", api.HeaderStreamStatus, got, test.want)
+			}
+		})
+	}
+}
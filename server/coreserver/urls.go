@@ -0,0 +1,116 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package coreserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/rs/zerolog/log"
+)
+
+type host struct {
+	Host string `json:"host"`
+	Surt string `json:"surt"`
+}
+
+type distinctUrl struct {
+	Url  string `json:"url"`
+	Surt string `json:"surt"`
+}
+
+// listHosts writes the distinct hosts matching the request as json lines, e.g. /hosts?url=*.no
+func (h Handler) listHosts(w http.ResponseWriter, r *http.Request) {
+	if h.UrlAPI == nil {
+		http.Error(w, "Url API not implemented", http.StatusNotImplemented)
+		return
+	}
+	h.listDistinct(w, r, "hosts", h.UrlAPI.ListHosts, func(key keyvalue.CdxKey) interface{} {
+		return host{Host: key.Host(), Surt: key.Domain()}
+	})
+}
+
+// listUrls writes the distinct urls matching the request as json lines, e.g. /urls?url=example.no/*
+//
+// The scheme, port and userinfo of an url are those of its first capture.
+func (h Handler) listUrls(w http.ResponseWriter, r *http.Request) {
+	if h.UrlAPI == nil {
+		http.Error(w, "Url API not implemented", http.StatusNotImplemented)
+		return
+	}
+	h.listDistinct(w, r, "urls", h.UrlAPI.ListUrls, func(key keyvalue.CdxKey) interface{} {
+		return distinctUrl{Url: key.Url(), Surt: key.Domain() + key.Path()}
+	})
+}
+
+func (h Handler) listDistinct(w http.ResponseWriter, r *http.Request, endpoint string, list func(context.Context, index.Request, chan<- keyvalue.CdxResponse) error, entry func(keyvalue.CdxKey) interface{}) {
+	coreAPI, err := api.Parse(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.Limits.Get(endpoint).Apply(r.Context(), coreAPI)
+	defer cancel()
+
+	response := make(chan keyvalue.CdxResponse)
+
+	if err := list(ctx, coreAPI, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msgf("Failed to list: %+v", coreAPI)
+		return
+	}
+
+	start := time.Now()
+	count := 0
+	defer func() {
+		log.Debug().Msgf("Found %d items in %s", count, time.Since(start))
+	}()
+
+	// the reason of truncation is only known when all results are written
+	w.Header().Set("Trailer", api.HeaderTruncated)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	stream := newStreamStatus(w, true)
+	defer stream.Close()
+
+	var searchErr error
+	enc := json.NewEncoder(w)
+	for res := range response {
+		if res.GetError() != nil {
+			log.Warn().Err(res.GetError()).Msg("failed result")
+			searchErr = res.GetError()
+			if err := stream.Error(res.GetError()); err != nil {
+				log.Warn().Err(err).Msg("failed to write error")
+				return
+			}
+			continue
+		}
+		if err := enc.Encode(entry(res.GetKey())); err != nil {
+			log.Warn().Err(err).Msg("failed to write result")
+			return
+		}
+		count++
+	}
+	if truncated := coreAPI.Truncation(count, searchErr); truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+}
@@ -0,0 +1,453 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcserver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Trailer metadata of a search.
+const (
+	// MetadataResumeKey is the resume key of a search that has more results.
+	MetadataResumeKey = "resume-key"
+	// MetadataTruncated is the reason the results of a search were truncated.
+	MetadataTruncated = "truncated"
+)
+
+// recordChunkSize is the maximum size of the chunks a record is streamed in.
+const recordChunkSize = 64 * 1024
+
+// Server implements the schema.IndexServer interface.
+type Server struct {
+	schema.UnimplementedIndexServer
+	CdxAPI     index.CdxAPI
+	FileAPI    index.FileAPI
+	IdAPI      index.IdAPI
+	ReportAPI  index.ReportAPI
+	WarcLoader loader.WarcLoader
+	// Limits are the query limits by endpoint.
+	Limits api.QueryLimits
+}
+
+func Register(s Server, r grpc.ServiceRegistrar) {
+	schema.RegisterIndexServer(r, s)
+}
+
+// errorCode returns the status code of err.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case index.IsBudgetExceeded(err):
+		return codes.ResourceExhausted
+	case errors.Is(err, index.InvalidResumeKeyError):
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// searchValues returns the parameters of the cdx api of the search request.
+func searchValues(in *schema.SearchRequest) url.Values {
+	values := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set(api.ParamUrl, in.GetUrl())
+	set(api.ParamMatchType, in.GetMatchType())
+	set(api.ParamSort, in.GetSort())
+	set(api.ParamClosest, in.GetClosest())
+	set(api.ParamFrom, in.GetFrom())
+	set(api.ParamTo, in.GetTo())
+	for _, filter := range in.GetFilter() {
+		values.Add(api.ParamFilter, filter)
+	}
+	if in.GetLimit() > 0 {
+		values.Set(api.ParamLimit, strconv.Itoa(int(in.GetLimit())))
+	}
+	set(api.ParamCollapse, in.GetCollapse())
+	set(api.ParamResumeKey, in.GetResumeKey())
+	return values
+}
+
+// listRequest returns the search request of the list request.
+func listRequest(in *schema.ListRequest) (*api.SearchRequest, error) {
+	values := url.Values{}
+	if in.GetLimit() > 0 {
+		values.Set(api.ParamLimit, strconv.Itoa(int(in.GetLimit())))
+	}
+	req, err := api.Parse(values)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return req, nil
+}
+
+func (s Server) Search(in *schema.SearchRequest, stream grpc.ServerStreamingServer[schema.Cdx]) error {
+	if s.CdxAPI == nil {
+		return status.Error(codes.Unimplemented, "search is not supported")
+	}
+	req, err := api.Parse(searchValues(in))
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx, cancel := s.Limits.Get("grpc").Apply(stream.Context(), req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := s.CdxAPI.Search(ctx, req, response); err != nil {
+		log.Error().Err(err).Msgf("Search failed: %+v", req)
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	start := time.Now()
+	count := 0
+	defer func() {
+		log.Debug().Str("request", in.String()).Msgf("Found %d items in %s", count, time.Since(start))
+	}()
+
+	var last *schema.Cdx
+	var searchErr, sendErr error
+	// the response is drained to let the search finish when the stream fails
+	for res := range response {
+		if sendErr != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			log.Warn().Err(err).Msg("failed result")
+			if searchErr == nil {
+				searchErr = err
+			}
+			continue
+		}
+		if err := stream.Send(res.GetCdx()); err != nil {
+			sendErr = err
+			cancel()
+			continue
+		}
+		last = res.GetCdx()
+		count++
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	md := metadata.MD{}
+	truncated := req.Truncation(count, searchErr)
+	if truncated != "" {
+		md.Set(MetadataTruncated, truncated)
+	}
+	// there may be more results if the limit was reached or the results were truncated
+	if last != nil && (truncated != "" || req.Limit() > 0 && count >= req.Limit()) {
+		md.Set(MetadataResumeKey, api.EncodeResumeKey(keyvalue.CdxKeyOf(last).String()))
+	}
+	stream.SetTrailer(md)
+
+	// a search truncated by its limits is not failed
+	if searchErr != nil && api.TruncatedBy(searchErr) == "" {
+		return status.Error(errorCode(searchErr), searchErr.Error())
+	}
+	return nil
+}
+
+func (s Server) GetStorageRef(ctx context.Context, in *schema.GetStorageRefRequest) (*schema.StorageRef, error) {
+	if s.IdAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "id index is not supported")
+	}
+	ref, err := s.IdAPI.GetStorageRef(ctx, in.GetId())
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get storage ref: %s", in.GetId())
+		return nil, status.Errorf(errorCode(err), "failed to get storage ref: %s: %v", in.GetId(), err)
+	}
+	if ref == "" {
+		return nil, status.Errorf(codes.NotFound, "storage ref not found: %s", in.GetId())
+	}
+	return &schema.StorageRef{Id: in.GetId(), Ref: ref}, nil
+}
+
+func (s Server) ListStorageRefs(in *schema.ListRequest, stream grpc.ServerStreamingServer[schema.StorageRef]) error {
+	if s.IdAPI == nil {
+		return status.Error(codes.Unimplemented, "id index is not supported")
+	}
+	req, err := listRequest(in)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	response := make(chan index.IdResponse)
+	if err := s.IdAPI.ListStorageRef(ctx, req, response); err != nil {
+		log.Error().Err(err).Msg("Failed to list ids")
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	var listErr, sendErr error
+	for res := range response {
+		if sendErr != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			log.Warn().Err(err).Msg("failed result")
+			if listErr == nil {
+				listErr = err
+			}
+			continue
+		}
+		if err := stream.Send(&schema.StorageRef{Id: res.GetId(), Ref: res.GetValue()}); err != nil {
+			sendErr = err
+			cancel()
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if listErr != nil {
+		return status.Error(errorCode(listErr), listErr.Error())
+	}
+	return nil
+}
+
+func (s Server) GetFileInfo(ctx context.Context, in *schema.GetFileInfoRequest) (*schema.FileInfo, error) {
+	if s.FileAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "file index is not supported")
+	}
+	fileInfo, err := s.FileAPI.GetFileInfo(ctx, in.GetName())
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get file info: %s", in.GetName())
+		return nil, status.Errorf(errorCode(err), "failed to get file info: %s: %v", in.GetName(), err)
+	}
+	if fileInfo == nil {
+		return nil, status.Errorf(codes.NotFound, "file info not found: %s", in.GetName())
+	}
+	return fileInfo, nil
+}
+
+func (s Server) ListFileInfos(in *schema.ListRequest, stream grpc.ServerStreamingServer[schema.FileInfo]) error {
+	if s.FileAPI == nil {
+		return status.Error(codes.Unimplemented, "file index is not supported")
+	}
+	req, err := listRequest(in)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	response := make(chan index.FileInfoResponse)
+	if err := s.FileAPI.ListFileInfo(ctx, req, response); err != nil {
+		log.Error().Err(err).Msg("Failed to list files")
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	var listErr, sendErr error
+	for res := range response {
+		if sendErr != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			log.Warn().Err(err).Msg("failed result")
+			if listErr == nil {
+				listErr = err
+			}
+			continue
+		}
+		if err := stream.Send(res.GetFileInfo()); err != nil {
+			sendErr = err
+			cancel()
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if listErr != nil {
+		return status.Error(errorCode(listErr), listErr.Error())
+	}
+	return nil
+}
+
+func (s Server) CreateReport(ctx context.Context, in *schema.SearchRequest) (*schema.Report, error) {
+	if s.ReportAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "reports are not supported")
+	}
+	req, err := api.Parse(searchValues(in))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	report, err := s.ReportAPI.CreateReport(ctx, req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate report")
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+	return report, nil
+}
+
+func (s Server) GetReport(ctx context.Context, in *schema.ReportRequest) (*schema.Report, error) {
+	if s.ReportAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "reports are not supported")
+	}
+	report, err := s.ReportAPI.GetReport(ctx, in.GetId())
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to get report: %s", in.GetId())
+		return nil, status.Errorf(errorCode(err), "failed to get report: %s: %v", in.GetId(), err)
+	}
+	if report == nil {
+		return nil, status.Errorf(codes.NotFound, "report not found: %s", in.GetId())
+	}
+	return report, nil
+}
+
+func (s Server) ListReports(in *schema.ListRequest, stream grpc.ServerStreamingServer[schema.Report]) error {
+	if s.ReportAPI == nil {
+		return status.Error(codes.Unimplemented, "reports are not supported")
+	}
+	req, err := listRequest(in)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	response := make(chan index.ReportResponse)
+	if err := s.ReportAPI.ListReports(ctx, req, response); err != nil {
+		log.Error().Err(err).Msg("Failed to list reports")
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	var listErr, sendErr error
+	for res := range response {
+		if sendErr != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			log.Warn().Err(err).Msg("failed report result")
+			if listErr == nil {
+				listErr = err
+			}
+			continue
+		}
+		if err := stream.Send(res.GetReport()); err != nil {
+			sendErr = err
+			cancel()
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if listErr != nil {
+		return status.Error(errorCode(listErr), listErr.Error())
+	}
+	return nil
+}
+
+func (s Server) CancelReport(ctx context.Context, in *schema.ReportRequest) (*emptypb.Empty, error) {
+	if s.ReportAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "reports are not supported")
+	}
+	if err := s.ReportAPI.CancelReport(ctx, in.GetId()); err != nil {
+		log.Error().Err(err).Msgf("Failed to cancel report: %s", in.GetId())
+		return nil, status.Errorf(errorCode(err), "failed to cancel report: %s: %v", in.GetId(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s Server) DeleteReport(ctx context.Context, in *schema.ReportRequest) (*emptypb.Empty, error) {
+	if s.ReportAPI == nil {
+		return nil, status.Error(codes.Unimplemented, "reports are not supported")
+	}
+	if err := s.ReportAPI.DeleteReport(ctx, in.GetId()); err != nil {
+		log.Error().Err(err).Msgf("Failed to delete report: %s", in.GetId())
+		return nil, status.Errorf(errorCode(err), "failed to delete report: %s: %v", in.GetId(), err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// chunkWriter writes to a stream of record chunks.
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[schema.RecordChunk]
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		size := min(len(p), recordChunkSize)
+		if err := c.stream.Send(&schema.RecordChunk{Data: p[:size]}); err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
+
+func (s Server) LoadRecord(in *schema.LoadRecordRequest, stream grpc.ServerStreamingServer[schema.RecordChunk]) error {
+	if s.WarcLoader == nil {
+		return status.Error(codes.Unimplemented, "records are not supported")
+	}
+	record, err := s.WarcLoader.LoadById(stream.Context(), in.GetId())
+	if record != nil {
+		defer record.Close()
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to load record: %s", in.GetId())
+		return status.Errorf(errorCode(err), "failed to load record '%s': %v", in.GetId(), err)
+	}
+	if record == nil {
+		return status.Errorf(codes.NotFound, "record not found: %s", in.GetId())
+	}
+
+	w := bufio.NewWriterSize(chunkWriter{stream: stream}, recordChunkSize)
+	marshaler := gowarc.NewMarshaler()
+	continuation := record
+	for continuation != nil {
+		continuation, _, err = marshaler.Marshal(w, continuation, 0)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to write record '%s': %v", in.GetId(), record)
+			return status.Errorf(codes.Internal, "failed to write record '%s': %v", in.GetId(), err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Warn().Err(err).Msgf("Failed to write record '%s': %v", in.GetId(), record)
+		return status.Errorf(codes.Internal, "failed to write record '%s': %v", in.GetId(), err)
+	}
+	return nil
+}
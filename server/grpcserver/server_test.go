@@ -0,0 +1,210 @@
+package grpcserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeFileAPI map[string]*schema.FileInfo
+
+func (f fakeFileAPI) GetFileInfo(_ context.Context, filename string) (*schema.FileInfo, error) {
+	return f[filename], nil
+}
+
+func (f fakeFileAPI) ListFileInfo(context.Context, index.Request, chan<- index.FileInfoResponse) error {
+	return errors.New("not implemented")
+}
+
+// fakeWarcLoader loads resource records with a block of the size given by the id.
+type fakeWarcLoader map[string]int
+
+func (f fakeWarcLoader) LoadById(_ context.Context, id string) (gowarc.WarcRecord, error) {
+	size, ok := f[id]
+	if !ok {
+		return nil, nil
+	}
+	builder := gowarc.NewRecordBuilder(gowarc.Resource, gowarc.WithAddMissingDigest(true), gowarc.WithAddMissingContentLength(true))
+	_, _ = builder.WriteString(strings.Repeat("a", size))
+	builder.AddWarcHeader(gowarc.WarcRecordID, "<"+id+">")
+	builder.AddWarcHeader(gowarc.WarcDate, "2020-04-01T22:22:00Z")
+	builder.AddWarcHeader(gowarc.WarcTargetURI, "http://example.com/")
+	builder.AddWarcHeader(gowarc.ContentType, "text/plain")
+	record, _, err := builder.Build()
+	return record, err
+}
+
+func (f fakeWarcLoader) LoadByStorageRef(context.Context, string) (gowarc.WarcRecord, error) {
+	return nil, errors.New("not implemented")
+}
+
+// newClient serves s and returns a client of it.
+func newClient(t *testing.T, s Server) schema.IndexClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	Register(s, server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return schema.NewIndexClient(conn)
+}
+
+func TestSearch(t *testing.T) {
+	ts := timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC))
+	cdxApi := indextest.CdxAPI{
+		{Uri: "http://example.com/", Ssu: "com,example)/", Sts: ts, Hsc: 200},
+		{Uri: "http://example.com/", Ssu: "com,example)/", Sts: ts, Hsc: 404},
+		{Uri: "http://example.org/", Ssu: "org,example)/", Sts: ts, Hsc: 200},
+	}
+	failing := indextest.FailingCdxAPI{CdxAPI: cdxApi, Err: errors.New("failed to unmarshal value")}
+	truncating := indextest.FailingCdxAPI{CdxAPI: cdxApi, After: 1, Err: index.MaxKeysExceededError}
+
+	tests := []struct {
+		name      string
+		cdxApi    index.CdxAPI
+		limits    api.QueryLimits
+		req       *schema.SearchRequest
+		results   int
+		code      codes.Code
+		truncated string
+		resume    bool
+	}{
+		{name: "exact", cdxApi: cdxApi, req: &schema.SearchRequest{Url: "http://example.com/"}, results: 2},
+		{name: "limit", cdxApi: cdxApi, req: &schema.SearchRequest{Url: "http://example.com/", Limit: 1}, results: 1, resume: true},
+		{name: "invalid", cdxApi: cdxApi, req: &schema.SearchRequest{Url: "http://example.com/", MatchType: "nope"}, code: codes.InvalidArgument},
+		{name: "max results", cdxApi: cdxApi, limits: api.QueryLimits{"grpc": {MaxResults: 1}}, req: &schema.SearchRequest{Url: "http://example.com/"}, results: 1, truncated: api.TruncatedMaxResults, resume: true},
+		{name: "max keys", cdxApi: truncating, req: &schema.SearchRequest{Url: "http://example.com/"}, results: 1, truncated: api.TruncatedMaxKeys, resume: true},
+		{name: "failed", cdxApi: failing, req: &schema.SearchRequest{Url: "http://example.com/"}, results: 2, code: codes.Internal},
+		{name: "unimplemented", req: &schema.SearchRequest{Url: "http://example.com/"}, code: codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(t, Server{CdxAPI: tt.cdxApi, Limits: tt.limits})
+
+			stream, err := client.Search(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results := 0
+			for {
+				_, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				results++
+			}
+			if !errors.Is(err, io.EOF) && status.Code(err) != tt.code || errors.Is(err, io.EOF) && tt.code != codes.OK {
+				t.Errorf("got error %v, want code %s", err, tt.code)
+			}
+			if results != tt.results {
+				t.Errorf("got %d results, want %d", results, tt.results)
+			}
+			trailer := stream.Trailer()
+			if got := first(trailer, MetadataTruncated); got != tt.truncated {
+				t.Errorf("got truncated %q, want %q", got, tt.truncated)
+			}
+			if got := first(trailer, MetadataResumeKey) != ""; got != tt.resume {
+				t.Errorf("got resume key %v, want %v", got, tt.resume)
+			}
+		})
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func TestGetFileInfo(t *testing.T) {
+	client := newClient(t, Server{FileAPI: fakeFileAPI{
+		"file.warc.gz": {Name: "file.warc.gz", Path: "/data/file.warc.gz", Size: 42},
+	}})
+
+	fileInfo, err := client.GetFileInfo(context.Background(), &schema.GetFileInfoRequest{Name: "file.warc.gz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileInfo.GetPath() != "/data/file.warc.gz" {
+		t.Errorf("got path %q, want %q", fileInfo.GetPath(), "/data/file.warc.gz")
+	}
+
+	_, err = client.GetFileInfo(context.Background(), &schema.GetFileInfoRequest{Name: "missing.warc.gz"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("got error %v, want code %s", err, codes.NotFound)
+	}
+}
+
+func TestLoadRecord(t *testing.T) {
+	id := "urn:uuid:e9a0cecc-0221-11e7-adb1-0242ac120008"
+	size := 3*recordChunkSize + 1
+	client := newClient(t, Server{WarcLoader: fakeWarcLoader{id: size}})
+
+	stream, err := client.LoadRecord(context.Background(), &schema.LoadRecordRequest{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunk.GetData()) > recordChunkSize {
+			t.Errorf("got chunk of %d bytes, want at most %d", len(chunk.GetData()), recordChunkSize)
+		}
+		buf.Write(chunk.GetData())
+	}
+	record, _, _, err := gowarc.NewUnmarshaler().Unmarshal(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := record.WarcHeader().GetId(gowarc.WarcRecordID); got != id {
+		t.Errorf("got record id %q, want %q", got, id)
+	}
+	if got := record.WarcHeader().Get(gowarc.ContentLength); got != strconv.Itoa(size) {
+		t.Errorf("got content length %s, want %d", got, size)
+	}
+
+	stream, err = client.LoadRecord(context.Background(), &schema.LoadRecordRequest{Id: "urn:uuid:missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("got error %v, want code %s", err, codes.NotFound)
+	}
+}
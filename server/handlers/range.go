@@ -0,0 +1,179 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoOverlap is returned when a range does not overlap the representation.
+var errNoOverlap = errors.New("invalid range: failed to overlap")
+
+// byteRange is a range of bytes of a representation.
+type byteRange struct {
+	start  int64
+	length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// parseRange parses the value of a Range header of a representation of size bytes.
+//
+// Only a single byte range is supported, ok is false if s is not one.
+func parseRange(s string, size int64) (br byteRange, ok bool, err error) {
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return br, false, nil
+	}
+	spec := strings.TrimSpace(s[len(b):])
+	if strings.Contains(spec, ",") {
+		return br, false, nil
+	}
+	first, last, found := strings.Cut(spec, "-")
+	if !found {
+		return br, false, nil
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+	if first == "" {
+		// suffix range of the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return br, false, nil
+		}
+		if n == 0 || size == 0 {
+			return br, true, errNoOverlap
+		}
+		n = min(n, size)
+		return byteRange{start: size - n, length: n}, true, nil
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return br, false, nil
+	}
+	if start >= size {
+		return br, true, errNoOverlap
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return br, false, nil
+		}
+		end = min(end, size-1)
+	}
+	return byteRange{start: start, length: end - start + 1}, true, nil
+}
+
+// checkIfRange reports whether the validator of the If-Range header of the request matches
+// the strong ETag or the Last-Modified date of the response header h.
+func checkIfRange(r *http.Request, h http.Header) bool {
+	ir := r.Header.Get("If-Range")
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		etag := h.Get("Etag")
+		// weak validators never match
+		return !strings.HasPrefix(ir, "W/") && !strings.HasPrefix(etag, "W/") && ir == etag
+	}
+	t, err := http.ParseTime(ir)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+	return err == nil && t.Truncate(time.Second).Equal(lastModified.Truncate(time.Second))
+}
+
+// serveRange sets the headers of the response to the request of a representation of
+// size bytes and returns the range of bytes to write and the status code of the response.
+func serveRange(w http.ResponseWriter, r *http.Request, size int64) (byteRange, int) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	full := byteRange{length: size}
+
+	s := r.Header.Get("Range")
+	if s == "" || !checkIfRange(r, w.Header()) {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		return full, http.StatusOK
+	}
+	br, ok, err := parseRange(s, size)
+	if !ok {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		return full, http.StatusOK
+	}
+	if err != nil {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return br, http.StatusRequestedRangeNotSatisfiable
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(br.length, 10))
+	w.Header().Set("Content-Range", br.contentRange(size))
+	return br, http.StatusPartialContent
+}
+
+// part is a part of a representation.
+type part struct {
+	io.Reader
+	size int64
+}
+
+// skip skips the next n bytes of r, by seeking if r is an io.Seeker.
+func skip(r io.Reader, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// copyRange writes the range of bytes of the representation made up of parts to w.
+// Parts before the range are skipped and parts after the range are not read.
+func copyRange(w io.Writer, parts []part, br byteRange) (int64, error) {
+	var written int64
+	start, remaining := br.start, br.length
+	for _, p := range parts {
+		if remaining <= 0 {
+			break
+		}
+		if start >= p.size {
+			start -= p.size
+			continue
+		}
+		if err := skip(p, start); err != nil {
+			return written, err
+		}
+		n, err := io.CopyN(w, p, min(p.size-start, remaining))
+		written += n
+		if err != nil {
+			return written, err
+		}
+		remaining -= n
+		start = 0
+	}
+	return written, nil
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		size  int64
+		want  byteRange
+		ok    bool
+		noOvl bool
+	}{
+		{name: "range", s: "bytes=10-19", size: 100, want: byteRange{start: 10, length: 10}, ok: true},
+		{name: "open range", s: "bytes=90-", size: 100, want: byteRange{start: 90, length: 10}, ok: true},
+		{name: "end past size", s: "bytes=90-200", size: 100, want: byteRange{start: 90, length: 10}, ok: true},
+		{name: "suffix", s: "bytes=-5", size: 100, want: byteRange{start: 95, length: 5}, ok: true},
+		{name: "suffix past size", s: "bytes=-500", size: 100, want: byteRange{start: 0, length: 100}, ok: true},
+		{name: "start past size", s: "bytes=100-", size: 100, ok: true, noOvl: true},
+		{name: "empty suffix", s: "bytes=-0", size: 100, ok: true, noOvl: true},
+		{name: "multiple ranges", s: "bytes=0-1,5-6", size: 100},
+		{name: "other unit", s: "items=0-1", size: 100},
+		{name: "end before start", s: "bytes=5-1", size: 100},
+		{name: "invalid", s: "bytes=a-b", size: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := parseRange(tt.s, tt.size)
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+			if (err != nil) != tt.noOvl {
+				t.Fatalf("got error %v, want error %v", err, tt.noOvl)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIfRange(t *testing.T) {
+	header := http.Header{}
+	header.Set("Etag", `"abc"`)
+	header.Set("Last-Modified", "Mon, 06 Mar 2017 04:02:06 GMT")
+
+	tests := []struct {
+		ifRange string
+		want    bool
+	}{
+		{ifRange: "", want: true},
+		{ifRange: `"abc"`, want: true},
+		{ifRange: `"def"`, want: false},
+		{ifRange: `W/"abc"`, want: false},
+		{ifRange: "Mon, 06 Mar 2017 04:02:06 GMT", want: true},
+		{ifRange: "Tue, 07 Mar 2017 04:02:06 GMT", want: false},
+		{ifRange: "not a date", want: false},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if tt.ifRange != "" {
+			r.Header.Set("If-Range", tt.ifRange)
+		}
+		if got := checkIfRange(r, header); got != tt.want {
+			t.Errorf("If-Range %q: got %v, want %v", tt.ifRange, got, tt.want)
+		}
+	}
+}
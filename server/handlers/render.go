@@ -17,16 +17,21 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/loader"
 )
 
 // RenderRecord renders gowarc.WarcRecord rec as a binary stream.
 func RenderRecord(w http.ResponseWriter, rec gowarc.WarcRecord) (int64, error) {
-	w.Header().Add("Content-Type", "application/octet-stream")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
 
 	marshaler := gowarc.NewMarshaler()
 
@@ -54,8 +59,8 @@ func RenderRedirect(w http.ResponseWriter, location string) {
 	w.WriteHeader(http.StatusFound)
 }
 
-func Render(w http.ResponseWriter, h http.Header, code int, r io.Reader) error {
-	// Write headers
+// setHeaders sets the headers of h on the response.
+func setHeaders(w http.ResponseWriter, h http.Header) {
 	for key, values := range h {
 		for i, value := range values {
 			if i == 0 {
@@ -65,6 +70,11 @@ func Render(w http.ResponseWriter, h http.Header, code int, r io.Reader) error {
 			}
 		}
 	}
+}
+
+func Render(w http.ResponseWriter, h http.Header, code int, r io.Reader) error {
+	// Write headers
+	setHeaders(w, h)
 
 	// Write status line
 	w.WriteHeader(code)
@@ -82,3 +92,67 @@ func Render(w http.ResponseWriter, h http.Header, code int, r io.Reader) error {
 
 	return nil
 }
+
+// RenderRange renders the payload r of size bytes like Render, but serves the byte range of
+// the Range header of the request with 206 Partial Content. If r is an io.Seeker the bytes
+// before the range are skipped by seeking.
+//
+// The payload is rendered without ranges if the size is unknown (negative) or the status code
+// is not 200 OK.
+func RenderRange(w http.ResponseWriter, req *http.Request, h http.Header, code int, r io.Reader, size int64) error {
+	if size < 0 || code != http.StatusOK || r == nil {
+		return Render(w, h, code, r)
+	}
+	setHeaders(w, h)
+
+	br, code := serveRange(w, req, size)
+	w.WriteHeader(code)
+	if code == http.StatusRequestedRangeNotSatisfiable {
+		return nil
+	}
+	_, err := copyRange(w, []part{{Reader: r, size: size}}, br)
+	if err != nil {
+		return fmt.Errorf("failed to write HTTP payload: %w", err)
+	}
+	return nil
+}
+
+// ServeRecord renders gowarc.WarcRecord rec as a binary stream like RenderRecord, but
+// serves the byte range of the Range header of the request with 206 Partial Content.
+// The block of records of uncompressed warc files is read from the offset of the range.
+func ServeRecord(w http.ResponseWriter, req *http.Request, rec gowarc.WarcRecord) (int64, error) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+
+	// the record is marshaled as by gowarc's marshaler
+	head := new(bytes.Buffer)
+	_, _ = fmt.Fprintf(head, "%v\r\n", rec.Version())
+	if _, err := rec.WarcHeader().Write(head); err != nil {
+		return 0, err
+	}
+	head.WriteString("\r\n")
+	blockSize, err := rec.WarcHeader().GetInt64(gowarc.ContentLength)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size of record block: %w", err)
+	}
+	var block io.Reader
+	if sr, ok := rec.(loader.SeekableRecord); ok {
+		block = sr.BlockSection()
+	} else if block, err = rec.Block().RawBytes(); err != nil {
+		return 0, err
+	}
+	parts := []part{
+		{Reader: head, size: int64(head.Len())},
+		{Reader: block, size: blockSize},
+		{Reader: strings.NewReader("\r\n\r\n"), size: 4},
+	}
+	size := int64(head.Len()) + blockSize + 4
+
+	br, code := serveRange(w, req, size)
+	w.WriteHeader(code)
+	if code == http.StatusRequestedRangeNotSatisfiable {
+		return 0, nil
+	}
+	return copyRange(w, parts, br)
+}
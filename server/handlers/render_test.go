@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/nlnwa/gowarcserver/client/clienttest"
+)
+
+func get(t *testing.T, url string, header map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the payload is not decoded by the client
+	req.Header.Set("Accept-Encoding", "gzip")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, b
+}
+
+func TestRange(t *testing.T) {
+	endpoints := map[string]string{
+		"resource": "/warcserver/web/20170306040206id_/http://example.com/",
+		"record":   "/record/urn:uuid:a9c51e3e-0221-11e7-bf66-0242ac120005",
+	}
+	// the ranges of uncompressed warc files are read by seeking
+	files := []string{"../../testdata/example.warc.gz", "../../testdata/example.warc"}
+
+	for _, file := range files {
+		server := clienttest.NewServer(t, clienttest.WithFiles(file))
+		for name, path := range endpoints {
+			t.Run(file+" "+name, func(t *testing.T) {
+				res, full := get(t, server.URL+path, nil)
+				if res.StatusCode != http.StatusOK {
+					t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusOK)
+				}
+				if res.Header.Get("Accept-Ranges") != "bytes" {
+					t.Errorf("got Accept-Ranges %q, want %q", res.Header.Get("Accept-Ranges"), "bytes")
+				}
+				if res.ContentLength != int64(len(full)) {
+					t.Errorf("got Content-Length %d, want %d", res.ContentLength, len(full))
+				}
+
+				res, part := get(t, server.URL+path, map[string]string{"Range": "bytes=10-19"})
+				if res.StatusCode != http.StatusPartialContent {
+					t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusPartialContent)
+				}
+				if !bytes.Equal(part, full[10:20]) {
+					t.Errorf("got %q, want %q", part, full[10:20])
+				}
+
+				res, part = get(t, server.URL+path, map[string]string{"Range": "bytes=-5"})
+				if res.StatusCode != http.StatusPartialContent || !bytes.Equal(part, full[len(full)-5:]) {
+					t.Errorf("got status %d and %q, want %d and %q", res.StatusCode, part, http.StatusPartialContent, full[len(full)-5:])
+				}
+
+				res, _ = get(t, server.URL+path, map[string]string{"Range": "bytes=100000-"})
+				if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+					t.Errorf("got status %d, want %d", res.StatusCode, http.StatusRequestedRangeNotSatisfiable)
+				}
+
+				res, part = get(t, server.URL+path, map[string]string{"Range": "bytes=10-19", "If-Range": `"nope"`})
+				if res.StatusCode != http.StatusOK || !bytes.Equal(part, full) {
+					t.Errorf("got status %d, want %d and the full representation", res.StatusCode, http.StatusOK)
+				}
+			})
+		}
+	}
+}
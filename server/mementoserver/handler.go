@@ -0,0 +1,292 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mementoserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	whatwgUrl "github.com/nlnwa/whatwg-url/url"
+	"github.com/rs/zerolog/log"
+)
+
+type Config struct {
+	// WebPath is the path of the endpoint that mementos are replayed from.
+	WebPath string
+	// Limits are the query limits by endpoint.
+	Limits api.QueryLimits
+}
+
+type Handler struct {
+	CdxAPI index.CdxAPI
+	Config *Config
+
+	// pathPrefix is the path prefix the handler is registered at
+	pathPrefix string
+}
+
+// timegateUrl returns the URI-G of uri.
+func (h Handler) timegateUrl(baseUrl string, uri string) string {
+	return baseUrl + h.pathPrefix + "/timegate/" + uri
+}
+
+// timemapUrl returns the URI-T of uri in format (link or json).
+func (h Handler) timemapUrl(baseUrl string, format string, uri string) string {
+	return baseUrl + h.pathPrefix + "/timemap/" + format + "/" + uri
+}
+
+// timemapRequest returns the url of r and the request of its replayable captures.
+func timemapRequest(r *http.Request) (string, *api.SearchRequest, error) {
+	uri := parseUrl(r)
+	u, err := whatwgUrl.Parse(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	return uri, api.MementoRequest(u), nil
+}
+
+// timemapLink writes the TimeMap of a url in the link format.
+func (h Handler) timemapLink(w http.ResponseWriter, r *http.Request) {
+	uri, req, err := timemapRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.Config.Limits.Get("memento-timemap").Apply(r.Context(), req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to search timemap")
+		return
+	}
+	baseUrl := BaseUrl(r)
+
+	start := time.Now()
+	count := 0
+	defer func() {
+		log.Debug().Str("url", uri).Msgf("Found %d mementos in %s", count, time.Since(start))
+	}()
+
+	write := func(cdx *schema.Cdx, rel string, sep string) error {
+		t := cdx.GetSts().AsTime()
+		link := Link(MementoUrl(baseUrl, h.Config.WebPath, t, cdx.GetUri()), "rel", rel, "datetime", Datetime(t))
+		_, err := io.WriteString(w, link+sep)
+		return err
+	}
+
+	// the previous memento is written when the next is known so that the last memento can be marked
+	var prev *schema.Cdx
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Warn().Err(err).Msg("failed result")
+			searchErr = err
+			continue
+		}
+		if prev == nil {
+			w.Header().Set("Content-Type", "application/link-format")
+			w.Header().Set("Trailer", api.HeaderTruncated)
+			header := []string{
+				Link(uri, "rel", "original"),
+				Link(h.timemapUrl(baseUrl, "link", uri), "rel", "self", "type", "application/link-format"),
+				Link(h.timegateUrl(baseUrl, uri), "rel", "timegate"),
+			}
+			if _, err := io.WriteString(w, strings.Join(header, ",\n")+",\n"); err != nil {
+				log.Warn().Err(err).Msg("failed to write timemap")
+				return
+			}
+		} else {
+			rel := "memento"
+			if count == 1 {
+				rel = "first memento"
+			}
+			if err := write(prev, rel, ",\n"); err != nil {
+				log.Warn().Err(err).Msg("failed to write timemap")
+				return
+			}
+		}
+		prev = res.GetCdx()
+		count++
+	}
+	if prev == nil {
+		http.NotFound(w, r)
+		return
+	}
+	rel := "last memento"
+	if count == 1 {
+		rel = "first last memento"
+	}
+	if err := write(prev, rel, "\n"); err != nil {
+		log.Warn().Err(err).Msg("failed to write timemap")
+		return
+	}
+	if truncated := req.Truncation(count, searchErr); truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+}
+
+type mementoJson struct {
+	Datetime string `json:"datetime"`
+	Uri      string `json:"uri"`
+}
+
+type timemapJson struct {
+	OriginalUri string `json:"original_uri"`
+	TimegateUri string `json:"timegate_uri"`
+	TimemapUri  struct {
+		LinkFormat string `json:"link_format"`
+		JsonFormat string `json:"json_format"`
+	} `json:"timemap_uri"`
+	Mementos struct {
+		First mementoJson   `json:"first"`
+		Last  mementoJson   `json:"last"`
+		List  []mementoJson `json:"list"`
+	} `json:"mementos"`
+}
+
+// timemapJson writes the TimeMap of a url as json.
+func (h Handler) timemapJson(w http.ResponseWriter, r *http.Request) {
+	uri, req, err := timemapRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := h.Config.Limits.Get("memento-timemap").Apply(r.Context(), req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to search timemap")
+		return
+	}
+	baseUrl := BaseUrl(r)
+
+	timemap := timemapJson{
+		OriginalUri: uri,
+		TimegateUri: h.timegateUrl(baseUrl, uri),
+	}
+	timemap.TimemapUri.LinkFormat = h.timemapUrl(baseUrl, "link", uri)
+	timemap.TimemapUri.JsonFormat = h.timemapUrl(baseUrl, "json", uri)
+
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Warn().Err(err).Msg("failed result")
+			searchErr = err
+			continue
+		}
+		t := res.GetCdx().GetSts().AsTime()
+		timemap.Mementos.List = append(timemap.Mementos.List, mementoJson{
+			Datetime: t.UTC().Format(time.RFC3339),
+			Uri:      MementoUrl(baseUrl, h.Config.WebPath, t, res.GetCdx().GetUri()),
+		})
+	}
+	list := timemap.Mementos.List
+	if len(list) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	timemap.Mementos.First = list[0]
+	timemap.Mementos.Last = list[len(list)-1]
+
+	if truncated := req.Truncation(len(list), searchErr); truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(timemap); err != nil {
+		log.Warn().Err(err).Msg("failed to write timemap")
+	}
+}
+
+// timegate redirects to the memento of a url closest to the Accept-Datetime request header,
+// or the most recent memento if the header is missing.
+func (h Handler) timegate(w http.ResponseWriter, r *http.Request) {
+	uri := parseUrl(r)
+	u, err := whatwgUrl.Parse(uri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t := time.Now()
+	if acceptDatetime := r.Header.Get("Accept-Datetime"); acceptDatetime != "" {
+		t, err = http.ParseTime(acceptDatetime)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid Accept-Datetime: %s", acceptDatetime), http.StatusBadRequest)
+			return
+		}
+	}
+
+	req := api.ClosestRequest(timestamp.TimeTo14(t), u)
+	ctx, cancel := h.Config.Limits.Get("memento-timegate").Apply(r.Context(), req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to search closest")
+		return
+	}
+	var cdx *schema.Cdx
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Warn().Err(err).Msg("failed result")
+			continue
+		}
+		// the first result is the closest, the rest are drained
+		if cdx == nil {
+			cdx = res.GetCdx()
+		}
+	}
+
+	baseUrl := BaseUrl(r)
+	w.Header().Set("Vary", "accept-datetime")
+	w.Header().Set("Link", strings.Join([]string{
+		Link(uri, "rel", "original"),
+		Link(h.timemapUrl(baseUrl, "link", uri), "rel", "timemap", "type", "application/link-format"),
+	}, ", "))
+	if cdx == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Location", MementoUrl(baseUrl, h.Config.WebPath, cdx.GetSts().AsTime(), cdx.GetUri()))
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusFound)
+}
@@ -0,0 +1,191 @@
+package mementoserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestServer(cdxs ...*schema.Cdx) *httprouter.Router {
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI(cdxs),
+		Config: &Config{WebPath: "/warcserver/web"},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+	return router
+}
+
+func newCdx(uri string, t time.Time) *schema.Cdx {
+	return &schema.Cdx{Uri: uri, Sts: timestamppb.New(t), Srt: "response"}
+}
+
+func TestTimemapLink(t *testing.T) {
+	uri := "http://example.com/"
+	router := newTestServer(
+		newCdx(uri, time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		newCdx(uri, time.Date(2021, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		newCdx(uri, time.Date(2022, time.April, 1, 22, 22, 0, 0, time.UTC)),
+	)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timemap/link/"+uri, nil))
+
+	want := `<http://example.com/>; rel="original",
+<http://localhost/timemap/link/http://example.com/>; rel="self"; type="application/link-format",
+<http://localhost/timegate/http://example.com/>; rel="timegate",
+<http://localhost/warcserver/web/20200401222200id_/http://example.com/>; rel="first memento"; datetime="Wed, 01 Apr 2020 22:22:00 GMT",
+<http://localhost/warcserver/web/20210401222200id_/http://example.com/>; rel="memento"; datetime="Thu, 01 Apr 2021 22:22:00 GMT",
+<http://localhost/warcserver/web/20220401222200id_/http://example.com/>; rel="last memento"; datetime="Fri, 01 Apr 2022 22:22:00 GMT"
+`
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timemap/link/http://example.org/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTimemapJson(t *testing.T) {
+	uri := "http://example.com/"
+	router := newTestServer(
+		newCdx(uri, time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		newCdx(uri, time.Date(2021, time.April, 1, 22, 22, 0, 0, time.UTC)),
+	)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timemap/json/"+uri, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got timemapJson
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Mementos.List) != 2 {
+		t.Fatalf("got %d mementos, want 2", len(got.Mementos.List))
+	}
+	if got.Mementos.Last.Datetime != "2021-04-01T22:22:00Z" {
+		t.Errorf("got last datetime %s, want 2021-04-01T22:22:00Z", got.Mementos.Last.Datetime)
+	}
+	if got.TimegateUri != "http://localhost/timegate/"+uri {
+		t.Errorf("got timegate %s", got.TimegateUri)
+	}
+}
+
+func TestTimemapLimits(t *testing.T) {
+	uri := "http://example.com/"
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{
+			newCdx(uri, time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+			newCdx(uri, time.Date(2021, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		},
+		Config: &Config{
+			WebPath: "/warcserver/web",
+			Limits:  api.QueryLimits{"memento-timemap": {MaxResults: 1}},
+		},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+
+	for _, format := range []string{"link", "json"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timemap/"+format+"/"+uri, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d, want %d", format, rec.Code, http.StatusOK)
+		}
+		if got := rec.Result().Header.Get(api.HeaderTruncated) + rec.Result().Trailer.Get(api.HeaderTruncated); got != api.TruncatedMaxResults {
+			t.Errorf("%s: got truncated %q, want %q", format, got, api.TruncatedMaxResults)
+		}
+	}
+}
+
+func TestTimegate(t *testing.T) {
+	uri := "http://example.com/"
+	router := newTestServer(
+		newCdx(uri, time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		newCdx(uri, time.Date(2021, time.April, 1, 22, 22, 0, 0, time.UTC)),
+	)
+
+	tests := []struct {
+		acceptDatetime string
+		status         int
+		location       string
+	}{
+		{
+			acceptDatetime: "Thu, 01 Aug 2020 00:00:00 GMT",
+			status:         http.StatusFound,
+			location:       "http://localhost/warcserver/web/20200401222200id_/http://example.com/",
+		},
+		{
+			acceptDatetime: "Thu, 01 Dec 2020 00:00:00 GMT",
+			status:         http.StatusFound,
+			location:       "http://localhost/warcserver/web/20210401222200id_/http://example.com/",
+		},
+		{
+			status:   http.StatusFound,
+			location: "http://localhost/warcserver/web/20210401222200id_/http://example.com/",
+		},
+		{
+			acceptDatetime: "yesterday",
+			status:         http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.acceptDatetime, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://localhost/timegate/"+uri, nil)
+			if test.acceptDatetime != "" {
+				req.Header.Set("Accept-Datetime", test.acceptDatetime)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Fatalf("got status %d, want %d", rec.Code, test.status)
+			}
+			if got := rec.Header().Get("Location"); got != test.location {
+				t.Errorf("got location %s, want %s", got, test.location)
+			}
+			if test.status == http.StatusFound && rec.Header().Get("Vary") != "accept-datetime" {
+				t.Errorf("missing Vary header")
+			}
+		})
+	}
+}
+
+func TestDefaultScheme(t *testing.T) {
+	uri := "http://example.com/"
+	router := newTestServer(
+		newCdx(uri, time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+	)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timegate/example.com/", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusFound)
+	}
+	if got, want := rec.Header().Get("Location"), "http://localhost/warcserver/web/20200401222200id_/http://example.com/"; got != want {
+		t.Errorf("got location %s, want %s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/timemap/link/example.com/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); !strings.HasPrefix(got, "<http://example.com/>; rel=\"original\"") {
+		t.Errorf("got:\n%s", got)
+	}
+}
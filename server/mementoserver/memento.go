@@ -0,0 +1,72 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mementoserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/timestamp"
+)
+
+// BaseUrl returns the scheme and host of r, e.g. "https://example.com".
+func BaseUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// MementoUrl returns the URI-M of the capture of uri at t replayed at webPath.
+func MementoUrl(baseUrl string, webPath string, t time.Time, uri string) string {
+	return baseUrl + webPath + "/" + timestamp.TimeTo14(t) + "id_/" + uri
+}
+
+// Datetime formats t as a Memento datetime (RFC 1123).
+func Datetime(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// Link formats a link of a Link header (RFC 8288) or a link format document (RFC 6690).
+//
+// Params are pairs of parameter names and values.
+func Link(uri string, params ...string) string {
+	var sb strings.Builder
+	sb.WriteString("<" + uri + ">")
+	for i := 0; i+1 < len(params); i += 2 {
+		_, _ = fmt.Fprintf(&sb, "; %s=\"%s\"", params[i], params[i+1])
+	}
+	return sb.String()
+}
+
+// parseUrl returns the url of a memento request, i.e. the url path parameter and query.
+// Urls without a scheme are prefixed with "http://".
+func parseUrl(r *http.Request) string {
+	uri := strings.TrimPrefix(httprouter.ParamsFromContext(r.Context()).ByName("url"), "/")
+	if q := r.URL.RawQuery; len(q) > 0 {
+		uri += "?" + q
+	}
+	if !api.SchemeRegExp.MatchString(uri) {
+		uri = "http://" + uri
+	}
+	return uri
+}
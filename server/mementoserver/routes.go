@@ -0,0 +1,32 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mementoserver
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Register registers the Memento (RFC 7089) TimeGate and TimeMap endpoints.
+func Register(h Handler, r *httprouter.Router, mw func(http.Handler) http.Handler, pathPrefix string) {
+	h.pathPrefix = pathPrefix
+	r.Handler("GET", pathPrefix+"/timemap/link/*url", mw(http.HandlerFunc(h.timemapLink)))
+	r.Handler("GET", pathPrefix+"/timemap/json/*url", mw(http.HandlerFunc(h.timemapJson)))
+	r.Handler("GET", pathPrefix+"/timegate/*url", mw(http.HandlerFunc(h.timegate)))
+	r.Handler("HEAD", pathPrefix+"/timegate/*url", mw(http.HandlerFunc(h.timegate)))
+}
@@ -17,9 +17,12 @@
 package warcserver
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/nlnwa/gowarcserver/schema"
@@ -50,6 +53,40 @@ func cdxToPywbJson(cdx *schema.Cdx) *pywbJson {
 	}
 }
 
+// pywbFields are the fields of pywbJson that results can be projected to.
+var pywbFields = map[string]func(*pywbJson) string{
+	"urlkey":    func(p *pywbJson) string { return p.Urlkey },
+	"timestamp": func(p *pywbJson) string { return p.Timestamp },
+	"url":       func(p *pywbJson) string { return p.Url },
+	"mime":      func(p *pywbJson) string { return p.Mime },
+	"status":    func(p *pywbJson) string { return p.Status },
+	"digest":    func(p *pywbJson) string { return p.Digest },
+	"length":    func(p *pywbJson) string { return p.Length },
+}
+
+// validatePywbFields returns an error if any of fields is not a field of pywbJson.
+func validatePywbFields(fields []string) error {
+	for _, field := range fields {
+		if _, ok := pywbFields[field]; !ok {
+			return fmt.Errorf("invalid %s: unknown field: %s", api.ParamFields, field)
+		}
+	}
+	return nil
+}
+
+// marshalPywbJson marshals cdx as pywb json projected to fields, or all fields if fields is empty.
+func marshalPywbJson(cdx *schema.Cdx, fields []string) ([]byte, error) {
+	p := cdxToPywbJson(cdx)
+	if len(fields) == 0 {
+		return json.Marshal(p)
+	}
+	m := make(map[string]string, len(fields))
+	for _, field := range fields {
+		m[field] = pywbFields[field](p)
+	}
+	return json.Marshal(m)
+}
+
 func parseClosest(u string, closest string) (*api.SearchRequest, error) {
 	uri, err := whatwgUrl.Parse(u)
 	if err != nil {
@@ -76,7 +113,7 @@ func parseResourceRequest(r *http.Request) (uri string, closest string) {
 	// closest parameter
 	p0 := params.ByName("timestamp")
 	// remove trailing 'id_'
-	closest = p0[:len(p0)-3]
+	closest = strings.TrimSuffix(p0, "id_")
 
 	// url parameter
 	p1 := params.ByName("url")
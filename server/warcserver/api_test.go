@@ -4,8 +4,11 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/schema"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestParseResourceRequest(t *testing.T) {
@@ -50,3 +53,37 @@ func TestParseResourceRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalPywbJson(t *testing.T) {
+	cdx := &schema.Cdx{
+		Ssu: "com,example,//:http:/",
+		Sts: timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC)),
+		Uri: "http://example.com/",
+		Hsc: 200,
+	}
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{
+			fields: []string{"url", "status"},
+			want:   `{"status":"200","url":"http://example.com/"}`,
+		},
+		{
+			fields: []string{"timestamp"},
+			want:   `{"timestamp":"20200401222200"}`,
+		},
+	}
+	for _, test := range tests {
+		got, err := marshalPywbJson(cdx, test.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+	if err := validatePywbFields([]string{"url", "nope"}); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
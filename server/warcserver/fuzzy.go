@@ -0,0 +1,491 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package warcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/surt"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	whatwgUrl "github.com/nlnwa/whatwg-url/url"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// fuzzyLookupLimit is the maximum number of urls considered by a fuzzy lookup.
+const fuzzyLookupLimit = 100
+
+// DefaultIgnoreParams are the query and path parameters of cache busters, sessions and
+// tracking ignored by the default fuzzy rule. A trailing "*" matches any suffix.
+var DefaultIgnoreParams = []string{"_", "cb", "cachebust", "cachebuster", "nocache", "rnd", "jsessionid", "phpsessid", "sid", "utm_*", "fbclid", "gclid"}
+
+// FuzzyRule is a rule of fuzzy matching of urls without an exact match.
+//
+// A fuzzy lookup is made of the captures with the url, canonicalized by removing the ignored
+// parameters, cut after ReplaceAfter as prefix. The best candidate is the one with the most
+// parameters in common with the url that is closest in time.
+type FuzzyRule struct {
+	// UrlPrefix are prefixes of the urlkeys (pywb's SURT, e.g. "com,example)/path") the rule
+	// applies to. The rule applies to all urls if empty.
+	UrlPrefix []string
+	// Match is a regular expression the urlkey must match for the rule to apply, or nil if
+	// the rule applies to all urls with the prefix. The groups of the match must be in the
+	// urls of the candidates.
+	Match *regexp.Regexp
+	// ReplaceAfter is where the url is cut to make the prefix of the lookup, "?" if empty.
+	ReplaceAfter string
+	// Filter are filters of the candidates, in pywb's syntax, where "{0}", "{1}"... are
+	// replaced by the groups of the match.
+	Filter []string
+	// IgnoreParams are the names of parameters removed when canonicalizing urls.
+	IgnoreParams []string
+}
+
+// FuzzyRules are fuzzy rules where the first rule that applies to a url is used.
+type FuzzyRules []FuzzyRule
+
+// DefaultFuzzyRule is the rule that applies to all urls.
+var DefaultFuzzyRule = FuzzyRule{IgnoreParams: DefaultIgnoreParams}
+
+// stringList is a list of strings that can be unmarshalled from a string or a list of strings.
+type stringList []string
+
+func (s *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = stringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// fuzzyLookup is the fuzzy_lookup of a rule, which is a regular expression or a mapping.
+type fuzzyLookup struct {
+	Match        string     `yaml:"match"`
+	Replace      string     `yaml:"replace"`
+	Filter       stringList `yaml:"filter"`
+	IgnoreParams stringList `yaml:"ignore_params"`
+}
+
+func (f *fuzzyLookup) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Match = value.Value
+		return nil
+	}
+	var lookup struct {
+		Match        yaml.Node  `yaml:"match"`
+		Replace      string     `yaml:"replace"`
+		Filter       stringList `yaml:"filter"`
+		IgnoreParams stringList `yaml:"ignore_params"`
+	}
+	if err := value.Decode(&lookup); err != nil {
+		return err
+	}
+	f.Replace = lookup.Replace
+	f.Filter = lookup.Filter
+	f.IgnoreParams = lookup.IgnoreParams
+	switch lookup.Match.Kind {
+	case yaml.ScalarNode:
+		f.Match = lookup.Match.Value
+	case yaml.MappingNode:
+		var match struct {
+			Regex string `yaml:"regex"`
+		}
+		if err := lookup.Match.Decode(&match); err != nil {
+			return err
+		}
+		f.Match = match.Regex
+	}
+	return nil
+}
+
+// ParseFuzzyRules parses the fuzzy rules of a YAML document in pywb's rules format. Rules
+// without fuzzy_lookup are ignored.
+func ParseFuzzyRules(b []byte) (FuzzyRules, error) {
+	var doc struct {
+		Rules []struct {
+			UrlPrefix   stringList   `yaml:"url_prefix"`
+			FuzzyLookup *fuzzyLookup `yaml:"fuzzy_lookup"`
+		} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	var rules FuzzyRules
+	for i, r := range doc.Rules {
+		if r.FuzzyLookup == nil {
+			continue
+		}
+		rule := FuzzyRule{
+			UrlPrefix:    r.UrlPrefix,
+			ReplaceAfter: r.FuzzyLookup.Replace,
+			Filter:       r.FuzzyLookup.Filter,
+			IgnoreParams: r.FuzzyLookup.IgnoreParams,
+		}
+		if r.FuzzyLookup.Match != "" {
+			re, err := regexp.Compile(r.FuzzyLookup.Match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fuzzy rule %d: %w", i, err)
+			}
+			rule.Match = re
+		}
+		if _, err := rule.filter(make([]string, 10)); err != nil {
+			return nil, fmt.Errorf("invalid fuzzy rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// LoadFuzzyRules loads the fuzzy rules of the YAML file at path.
+func LoadFuzzyRules(path string) (FuzzyRules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseFuzzyRules(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fuzzy rules: %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// urlKey returns the SURT of u in pywb's format, e.g. "com,example)/path?query".
+func urlKey(u string) string {
+	s, err := surt.SurtS(u, false)
+	if err != nil {
+		return u
+	}
+	host, rest, _ := strings.Cut(strings.TrimPrefix(s, "("), ")")
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ","), ",www")
+	return strings.ToLower(host) + ")" + rest
+}
+
+// find returns the first rule that applies to the urlkey and the groups of its match.
+func (rules FuzzyRules) find(key string) (*FuzzyRule, []string) {
+	for i := range rules {
+		rule := &rules[i]
+		applies := len(rule.UrlPrefix) == 0
+		for _, prefix := range rule.UrlPrefix {
+			if strings.HasPrefix(key, prefix) {
+				applies = true
+				break
+			}
+		}
+		if !applies {
+			continue
+		}
+		if rule.Match == nil {
+			return rule, nil
+		}
+		if m := rule.Match.FindStringSubmatch(key); m != nil {
+			return rule, m[1:]
+		}
+	}
+	return nil, nil
+}
+
+// pywbFilterOps maps the operators of pywb's filters to the operators of filters of the index.
+var pywbFilterOps = map[byte]string{'~': "", '=': "="}
+
+// filter returns the filter of the candidates of the rule with the groups of the match.
+func (rule *FuzzyRule) filter(groups []string) (api.Filter, error) {
+	var filters []string
+	for _, f := range rule.Filter {
+		for i, group := range groups {
+			f = strings.ReplaceAll(f, fmt.Sprintf("{%d}", i), group)
+		}
+		invert := strings.HasPrefix(f, "!")
+		f = strings.TrimPrefix(f, "!")
+		// pywb's filters are regular expressions unless prefixed with "~" (contains) or "=" (exact)
+		op := "~"
+		if len(f) > 0 {
+			if o, ok := pywbFilterOps[f[0]]; ok {
+				op, f = o, f[1:]
+			}
+		}
+		if invert {
+			op = "!" + op
+		}
+		filters = append(filters, op+f)
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return api.ParseFilter(filters, map[string]string{
+		"urlkey": "ssu",
+		"status": "hsc",
+		"mime":   "mct",
+		"url":    "uri",
+	})
+}
+
+// ignored reports whether the parameter name is ignored by the rule.
+func (rule *FuzzyRule) ignored(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range rule.IgnoreParams {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// params returns the query parameters of u that are not ignored.
+func (rule *FuzzyRule) params(u string) map[string]string {
+	params := make(map[string]string)
+	_, query, _ := strings.Cut(u, "?")
+	query, _, _ = strings.Cut(query, "#")
+	for _, pair := range strings.Split(query, "&") {
+		name, value, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(name); err == nil && name != "" && !rule.ignored(name) {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// canonicalize returns u without the ignored path and query parameters.
+func (rule *FuzzyRule) canonicalize(u string) string {
+	u, fragment, hasFragment := strings.Cut(u, "#")
+	base, query, hasQuery := strings.Cut(u, "?")
+
+	// path parameters, e.g. ";jsessionid=..."
+	segments := strings.Split(base, ";")
+	kept := segments[:1]
+	for _, segment := range segments[1:] {
+		name, _, _ := strings.Cut(segment, "=")
+		if !rule.ignored(name) {
+			kept = append(kept, segment)
+		}
+	}
+	u = strings.Join(kept, ";")
+
+	if hasQuery {
+		var pairs []string
+		for _, pair := range strings.Split(query, "&") {
+			name, _, _ := strings.Cut(pair, "=")
+			if name, err := url.QueryUnescape(name); err == nil && rule.ignored(name) {
+				continue
+			}
+			pairs = append(pairs, pair)
+		}
+		if len(pairs) > 0 {
+			u += "?" + strings.Join(pairs, "&")
+		}
+	}
+	if hasFragment {
+		u += "#" + fragment
+	}
+	return u
+}
+
+// cut returns the url cut after the first ReplaceAfter of the rule, without scheme.
+func (rule *FuzzyRule) cut(u string) string {
+	replaceAfter := rule.ReplaceAfter
+	if replaceAfter == "" {
+		replaceAfter = "?"
+	}
+	if _, rest, ok := strings.Cut(u, "://"); ok {
+		u = rest
+	}
+	if i := strings.Index(u, replaceAfter); i >= 0 {
+		u = u[:i]
+	}
+	return u
+}
+
+// score returns the similarity of the parameters of the candidate and the wanted parameters,
+// where a common parameter with the same value counts more than one with another value, and
+// parameters of the candidate that are not wanted count against it.
+func (rule *FuzzyRule) score(want map[string]string, candidate string) int {
+	score := 0
+	got := rule.params(candidate)
+	for name, value := range want {
+		if v, ok := got[name]; ok {
+			score++
+			if v == value {
+				score++
+			}
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			score--
+		}
+	}
+	return score
+}
+
+// fuzzyLookup looks up the best capture of uri by the fuzzy rules, or nil if there is none.
+func (h Handler) fuzzyLookup(ctx context.Context, uri string, closest string) (*schema.Cdx, error) {
+	rule, groups := h.Config.FuzzyRules.find(urlKey(uri))
+	if rule == nil {
+		return nil, nil
+	}
+	limits := h.Config.Limits.Get("warcserver-web")
+
+	// an exact lookup of the canonical url
+	canonical := rule.canonicalize(uri)
+	if canonical != uri {
+		u, err := whatwgUrl.Parse(canonical)
+		if err != nil {
+			return nil, err
+		}
+		req := api.ClosestRequest(closest, u)
+		lookupCtx, cancel := limits.Apply(ctx, req)
+		cdx, err := searchClosest(lookupCtx, h.CdxAPI, req)
+		cancel()
+		if err != nil || cdx != nil {
+			return cdx, err
+		}
+	}
+
+	// a prefix lookup of the urls of the canonical url cut after the replacement
+	filter, err := rule.filter(groups)
+	if err != nil {
+		return nil, err
+	}
+	u, err := whatwgUrl.Parse(canonical)
+	if err != nil {
+		return nil, err
+	}
+	req := api.PrefixRequest(u, filter)
+	prefixCtx, cancel := limits.Apply(ctx, req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(prefixCtx, req, response); err != nil {
+		return nil, err
+	}
+
+	prefix := rule.cut(canonical)
+	want := rule.params(canonical)
+
+	// the candidates are the urls with the best score
+	seen := make(map[string]bool)
+	var candidates []string
+	bestScore := 0
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if searchErr == nil {
+				searchErr = err
+			}
+			continue
+		}
+		candidate := res.GetCdx().GetUri()
+		if seen[candidate] || len(seen) >= fuzzyLookupLimit {
+			continue
+		}
+		seen[candidate] = true
+		if rule.cut(rule.canonicalize(candidate)) != prefix || !containsAll(urlKey(candidate), groups) {
+			continue
+		}
+		score := rule.score(want, candidate)
+		if len(candidates) == 0 || score > bestScore {
+			candidates = []string{candidate}
+			bestScore = score
+		} else if score == bestScore {
+			candidates = append(candidates, candidate)
+		}
+		// the lookup ends when enough urls are seen
+		if len(seen) >= fuzzyLookupLimit {
+			cancel()
+		}
+	}
+	if len(candidates) == 0 && searchErr != nil && !errors.Is(searchErr, context.Canceled) {
+		return nil, searchErr
+	}
+
+	// the best capture is the closest capture of the candidates
+	closestTime, err := timestamp.Parse(closest)
+	if err != nil {
+		return nil, err
+	}
+	var best *schema.Cdx
+	for _, candidate := range candidates {
+		cdx, err := h.closestMatch(ctx, limits, candidate, closest, filter)
+		if err != nil {
+			return nil, err
+		}
+		if cdx != nil && (best == nil || cdx.GetSts().AsTime().Sub(closestTime).Abs() < best.GetSts().AsTime().Sub(closestTime).Abs()) {
+			best = cdx
+		}
+	}
+	if best != nil {
+		log.Debug().Str("url", uri).Str("match", best.GetUri()).Msg("Fuzzy matched")
+	}
+	return best, nil
+}
+
+// closestMatch returns the closest capture of uri that passes filter, or nil if there is none.
+func (h Handler) closestMatch(ctx context.Context, limits api.Limits, uri string, closest string, filter api.Filter) (*schema.Cdx, error) {
+	u, err := whatwgUrl.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	req := api.ClosestRequest(closest, u)
+	ctx, cancel := limits.Apply(ctx, req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		return nil, err
+	}
+	var match *schema.Cdx
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if searchErr == nil {
+				searchErr = err
+			}
+			continue
+		}
+		if match == nil && filter.Eval(res.GetCdx()) {
+			match = res.GetCdx()
+		}
+	}
+	if match == nil && searchErr != nil && !errors.Is(searchErr, context.Canceled) {
+		return nil, searchErr
+	}
+	return match, nil
+}
+
+// containsAll reports whether s contains all of the substrings.
+func containsAll(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if !strings.Contains(s, substring) {
+			return false
+		}
+	}
+	return true
+}
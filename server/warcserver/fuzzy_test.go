@@ -0,0 +1,176 @@
+package warcserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/timestamp"
+)
+
+func TestParseFuzzyRules(t *testing.T) {
+	rules, err := ParseFuzzyRules([]byte(`
+rules:
+  - url_prefix: 'com,example)/'
+    rewrite:
+      js_regexs: []
+  - url_prefix: ['com,example)/api', 'com,example)/v2']
+    fuzzy_lookup: '[?&](id=[^&]+)'
+  - url_prefix: 'org,example)/'
+    fuzzy_lookup:
+      match:
+        regex: '[?&](q=[^&]+)'
+        args: []
+      replace: '&'
+      filter: ['~urlkey:{0}', '!status:404']
+      ignore_params: ['session*']
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want %d", len(rules), 2)
+	}
+	if got := rules[0].UrlPrefix; len(got) != 2 || got[1] != "com,example)/v2" {
+		t.Errorf("got url prefix %v", got)
+	}
+	if rules[0].Match == nil || rules[0].Match.String() != "[?&](id=[^&]+)" {
+		t.Errorf("got match %v", rules[0].Match)
+	}
+	rule := rules[1]
+	if rule.Match == nil || rule.Match.String() != "[?&](q=[^&]+)" {
+		t.Errorf("got match %v", rule.Match)
+	}
+	if rule.ReplaceAfter != "&" || len(rule.Filter) != 2 || !rule.ignored("SessionId") {
+		t.Errorf("got rule %+v", rule)
+	}
+
+	if _, err := ParseFuzzyRules([]byte("rules:\n  - fuzzy_lookup: '('\n")); err == nil {
+		t.Error("expected error of invalid regular expression")
+	}
+}
+
+func TestFuzzyRuleCanonicalize(t *testing.T) {
+	rule := DefaultFuzzyRule
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://example.com/a?id=1", want: "http://example.com/a?id=1"},
+		{url: "http://example.com/a?_=123&id=1&utm_source=x", want: "http://example.com/a?id=1"},
+		{url: "http://example.com/a;jsessionid=abc?cb=1", want: "http://example.com/a"},
+		{url: "http://example.com/a?id=1&_=2#top", want: "http://example.com/a?id=1#top"},
+	}
+	for _, tt := range tests {
+		if got := rule.canonicalize(tt.url); got != tt.want {
+			t.Errorf("canonicalize(%q): got %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestResourceFuzzy(t *testing.T) {
+	archive := fakeArchive{
+		{uri: "http://example.com/a?id=1", ts: "20200101000000", status: 200, body: "a"},
+		{uri: "http://example.com/b?id=1&x=3", ts: "20200101000000", status: 200, body: "b1"},
+		{uri: "http://example.com/b?id=2&y=1", ts: "20200101000000", status: 200, body: "b2"},
+		{uri: "http://example.com/c?t=1", ts: "20190101000000", status: 200, body: "c1"},
+		{uri: "https://example.com/c?t=2", ts: "20200101000000", status: 200, body: "c2"},
+		{uri: "http://example.com/d?id=5&v=2", ts: "20190101000000", status: 200, body: "d5"},
+		{uri: "http://example.com/d?id=6&v=1", ts: "20200101000000", status: 200, body: "d6"},
+		{uri: "http://example.com/e/f?id=1", ts: "20200101000000", status: 200, body: "f"},
+	}
+	// more captures of a url family than the urls considered by a lookup, where the url with
+	// the most parameters in common comes last and its closest capture is the newest
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		archive = append(archive, capture{uri: "http://example.com/g?a=" + strconv.Itoa(i), ts: timestamp.TimeTo14(start), status: 200, body: "a"})
+	}
+	for i := 0; i <= fuzzyLookupLimit; i++ {
+		archive = append(archive, capture{uri: "http://example.com/g?id=1", ts: timestamp.TimeTo14(start.AddDate(0, 0, i)), status: 200, body: "g" + strconv.Itoa(i)})
+	}
+	rules, err := ParseFuzzyRules([]byte(`
+rules:
+  - url_prefix: 'com,example)/d'
+    fuzzy_lookup: '[?&](id=[^&]+)'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules = append(rules, DefaultFuzzyRule)
+
+	tests := []struct {
+		name   string
+		url    string
+		rules  FuzzyRules
+		status int
+		body   string
+	}{
+		{
+			name:   "disabled",
+			url:    "http://example.com/a?id=1&_=999",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "canonical",
+			url:    "http://example.com/a?id=1&_=999",
+			rules:  rules,
+			status: http.StatusOK,
+			body:   "a",
+		},
+		{
+			name:   "most parameters in common",
+			url:    "http://example.com/b?id=1&x=2",
+			rules:  rules,
+			status: http.StatusOK,
+			body:   "b1",
+		},
+		{
+			name:   "closest in time",
+			url:    "http://example.com/c?t=3",
+			rules:  rules,
+			status: http.StatusOK,
+			body:   "c2",
+		},
+		{
+			name:   "match of rule",
+			url:    "http://example.com/d?id=5&v=1",
+			rules:  rules,
+			status: http.StatusOK,
+			body:   "d5",
+		},
+		{
+			name:   "many captures",
+			url:    "http://example.com/g?id=1&x=1",
+			rules:  rules,
+			status: http.StatusOK,
+			body:   "g" + strconv.Itoa(fuzzyLookupLimit),
+		},
+		{
+			name:   "other path",
+			url:    "http://example.com/e?id=1",
+			rules:  rules,
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			Register(Handler{CdxAPI: archive, WarcLoader: archive, Config: &Config{FuzzyRules: tt.rules}}, router, func(h http.Handler) http.Handler { return h }, "")
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/web/20200101000000id_/"+tt.url, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" {
+				if got, _ := io.ReadAll(rec.Body); string(got) != tt.body {
+					t.Errorf("got body %q, want %q", got, tt.body)
+				}
+			}
+		})
+	}
+}
@@ -2,26 +2,54 @@ package warcserver
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/nlnwa/gowarc"
 	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
 	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
 	"github.com/nlnwa/gowarcserver/server/api"
-	"github.com/nlnwa/gowarcserver/server/handlers"
+	"github.com/nlnwa/gowarcserver/server/mementoserver"
 	"github.com/nlnwa/gowarcserver/timestamp"
-	urlErrors "github.com/nlnwa/whatwg-url/errors"
-	"github.com/nlnwa/whatwg-url/url"
 	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
 	PrefixSearchLimit int
+	// Collection is the name of the collection reported in the
+	// Warcserver-Source-Coll header of records.
+	Collection string
+	// MementoPath is the path prefix of the Memento TimeGate and TimeMap
+	// endpoints, or empty if they are not served.
+	MementoPath string
+	// Limits are the query limits by endpoint.
+	Limits api.QueryLimits
+	// SkipSelfRedirects makes the resource endpoint skip captures of redirects to the
+	// same resource at the same timestamp in favour of the next-closest capture.
+	SkipSelfRedirects bool
+	// MaxRedirectHops is the maximum number of redirects the resource endpoint follows
+	// before redirecting the client.
+	MaxRedirectHops int
+	// MaxRedirectDrift is the maximum time between the capture of a redirect and the
+	// capture of its target, or zero if unlimited. Captures of redirects to targets
+	// further away are skipped.
+	MaxRedirectDrift time.Duration
+	// PayloadDecoding is how the payloads of the resource endpoint are decoded, unless
+	// requested otherwise by the Warcserver-Payload-Decoding header.
+	PayloadDecoding loader.PayloadDecoding
+	// FuzzyRules are the rules of fuzzy matching of urls the resource endpoint finds no
+	// capture of, or nil if urls are not fuzzy matched.
+	FuzzyRules FuzzyRules
+}
+
+// handlesRedirects reports whether the resource endpoint is configured to skip or follow
+// captures of redirects.
+func (c *Config) handlesRedirects() bool {
+	return c.SkipSelfRedirects || c.MaxRedirectHops > 0 || c.MaxRedirectDrift > 0
 }
 
 type Handler struct {
@@ -38,6 +66,16 @@ func (h Handler) index(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	switch coreAPI.Output() {
+	case "", api.OutputCdxj, api.OutputJson:
+	default:
+		http.Error(w, fmt.Sprintf("%s=%s is not supported", api.ParamOutput, coreAPI.Output()), http.StatusBadRequest)
+		return
+	}
+	if err := validatePywbFields(coreAPI.Fields()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// limit the number of results when prefix searching
 	if coreAPI.MatchType() == index.MatchTypePrefix &&
@@ -56,17 +94,27 @@ func (h Handler) index(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Str("request", fmt.Sprintf("%+v", coreAPI)).Msgf("Found %d items in %s", count, time.Since(start))
 	}()
 
-	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	ctx, cancel := h.Config.Limits.Get("warcserver-cdx").Apply(r.Context(), coreAPI)
 	defer cancel()
 
 	response := make(chan index.CdxResponse)
 
 	if err = h.CdxAPI.Search(ctx, coreAPI, response); err != nil {
+		if errors.Is(err, index.InvalidResumeKeyError) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		log.Error().Err(err).Msgf("Search failed: %+v", coreAPI)
 		return
 	}
 
+	// the resume key and the reason of truncation are only known when all results are written
+	w.Header().Add("Trailer", api.HeaderResumeKey)
+	w.Header().Add("Trailer", api.HeaderTruncated)
+
+	var last *schema.Cdx
+	var searchErr error
 	for res := range response {
 		err := res.GetError()
 		if errors.Is(err, context.Canceled) {
@@ -74,10 +122,11 @@ func (h Handler) index(w http.ResponseWriter, r *http.Request) {
 		}
 		if err != nil {
 			log.Warn().Err(err).Msg("failed result")
+			searchErr = err
 			continue
 		}
 		cdx := res.GetCdx()
-		cdxj, err := json.Marshal(cdxToPywbJson(cdx))
+		cdxj, err := marshalPywbJson(cdx, coreAPI.Fields())
 		if err != nil {
 			log.Warn().Err(err).Msg("failed to marshal result")
 			continue
@@ -94,36 +143,71 @@ func (h Handler) index(w http.ResponseWriter, r *http.Request) {
 			log.Warn().Err(err).Msg("failed to write result")
 			return
 		}
+		last = cdx
 		count++
 	}
+	truncated := coreAPI.Truncation(count, searchErr)
+	if truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+	// there may be more results if the limit was reached or the results were truncated
+	if last != nil && (truncated != "" || coreAPI.Limit() > 0 && count >= coreAPI.Limit()) {
+		w.Header().Set(api.HeaderResumeKey, api.EncodeResumeKey(keyvalue.CdxKeyOf(last).String()))
+	}
+}
+
+// RevisitResolver resolves revisit records without WARC-Refers-To by looking up
+// the closest capture of the target URI to the date.
+type RevisitResolver struct {
+	CdxAPI index.CdxAPI
 }
 
-// resolveRevisit resolves a revisit record by looking up the closest matching target URI and date
-func (h Handler) resolveRevisit(ctx context.Context, targetURI string, closest string) (string, error) {
+// ResolveRevisit returns the storage ref of the closest capture of targetURI to date, or
+// an empty string if there is none.
+func (rr RevisitResolver) ResolveRevisit(ctx context.Context, targetURI string, date string) (string, error) {
+	closest, err := timestamp.To14(date)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse WARC-Refers-To-Date: %s: %w", date, err)
+	}
 	closestReq, err := parseClosest(targetURI, closest)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse WARC-Refers-To-Target-URI: %s: %w", targetURI, err)
 	}
 
-	response := make(chan index.CdxResponse)
-	err = h.CdxAPI.Search(ctx, closestReq, response)
+	cdx, err := searchClosest(ctx, rr.CdxAPI, closestReq)
 	if err != nil {
 		return "", fmt.Errorf("failed to get closest match: %s %s: %w", targetURI, closest, err)
 	}
-
 	// we are looking for the record's storage ref
-	var ref string
+	return cdx.GetRef(), nil
+}
+
+// searchClosest returns the first result of the search, or nil if there is none.
+func searchClosest(ctx context.Context, cdxAPI index.CdxAPI, req index.Request) (*schema.Cdx, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
+	response := make(chan index.CdxResponse)
+	if err := cdxAPI.Search(ctx, req, response); err != nil {
+		return nil, err
+	}
+
+	var cdx *schema.Cdx
 	for res := range response {
-		if res.GetError() != nil {
-			log.Warn().Err(err).Msgf("error when iterating response of closest search: %s %s", targetURI, closest)
+		if cdx != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msgf("error when iterating response of closest search: %s", req.Url())
+			}
 			continue
 		}
-		ref = res.GetCdx().GetRef()
-		break
+		cdx = res.GetCdx()
+		// stop the search but drain the response
+		cancel()
 	}
-
-	return ref, nil
+	return cdx, nil
 }
 
 func (h Handler) resource(w http.ResponseWriter, r *http.Request) {
@@ -134,6 +218,11 @@ func (h Handler) resource(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	decoding, err := h.payloadDecoding(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	log := log.With().Str("closest", closest).Str("url", uri).Logger()
 
@@ -142,7 +231,8 @@ func (h Handler) resource(w http.ResponseWriter, r *http.Request) {
 		log.Debug().Str("duration", time.Since(start).String()).Msg("Fetched resource")
 	}()
 
-	ctx, cancelQuery := context.WithTimeout(r.Context(), 10*time.Second)
+	limits := h.Config.Limits.Get("warcserver-web")
+	ctx, cancelQuery := limits.Apply(r.Context(), closestAPI)
 	defer cancelQuery()
 
 	// query API
@@ -154,156 +244,144 @@ func (h Handler) resource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var res index.CdxResponse
-	for res = range response {
+	// the captures in order of closeness are candidates when captures of redirects are skipped
+	var candidates []*schema.Cdx
+	var searchErr error
+	for res := range response {
 		err := res.GetError()
-		if errors.Is(err, context.Canceled) {
-			return
-		}
 		if err != nil {
-			log.Warn().Err(err).Msg("Failed cdx response")
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("Failed cdx response")
+			}
+			if searchErr == nil {
+				searchErr = err
+			}
 			continue
 		}
-		if res.GetCdx() == nil {
-			http.NotFound(w, r)
-			return
+		if res.GetCdx() != nil {
+			candidates = append(candidates, res.GetCdx())
 		}
-		break
 	}
-	if res == nil {
-		http.NotFound(w, r)
-		return
-
-	}
-
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
-
-	ref := res.GetCdx().GetRef()
-
-	// load warc record by storage ref
-	var warcRecord gowarc.WarcRecord
-	retry := true
-	for warcRecord == nil {
-		warcRecord, err = h.WarcLoader.LoadByStorageRef(ctx, ref)
-		var errResolveRevisit loader.ErrResolveRevisit
-		if errors.As(err, &errResolveRevisit) && retry {
-			var date string
-			if date, err = timestamp.To14(errResolveRevisit.Date); err == nil {
-				ref, err = h.resolveRevisit(ctx, errResolveRevisit.TargetURI, date)
-			}
-			retry = false
+	if len(candidates) == 0 {
+		// the lookup ends when the limits are exceeded
+		if truncated := closestAPI.Truncation(0, searchErr); truncated != "" {
+			w.Header().Set(api.HeaderTruncated, truncated)
+			http.Error(w, searchErr.Error(), http.StatusServiceUnavailable)
+			return
 		}
-		var errWarcRefersToNotFound loader.ErrWarcRefersToNotFound
-		if errors.As(err, &errWarcRefersToNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			log.Error().Err(err).Msgf("Failed to load record")
+		if errors.Is(searchErr, context.Canceled) {
+			return
+		}
+		if h.Config.FuzzyRules == nil {
+			http.NotFound(w, r)
 			return
 		}
+		cdx, err := h.fuzzyLookup(r.Context(), uri, closestAPI.Closest())
 		if err != nil {
-			if warcRecord != nil {
-				_ = warcRecord.Close()
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("Failed fuzzy lookup")
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Error().Err(err).Msgf("Failed to load record")
+			http.NotFound(w, r)
+			return
+		}
+		if cdx == nil {
+			http.NotFound(w, r)
 			return
 		}
+		candidates = append(candidates, cdx)
 	}
-	defer warcRecord.Close()
 
-	block, ok := warcRecord.Block().(gowarc.HttpResponseBlock)
-	if !ok {
-		err := fmt.Errorf("record not renderable: %s", warcRecord)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Error().Err(err).Msg("Failed to load resource")
-		return
-	}
+	ctx, cancel := limits.Context(r.Context())
+	defer cancel()
 
-	s := block.HttpStatusCode()
+	// storage refs of the captures loaded, to not follow redirects in circles
+	visited := make(map[string]bool)
 
-	if !isRedirect(s) {
-		p, err := block.PayloadBytes()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Error().Err(err).Msg("Failed to load resource")
-			return
-		}
-		err = handlers.Render(w, *block.HttpHeader(), block.HttpStatusCode(), p)
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to load resource")
+candidates:
+	for _, cdx := range candidates {
+		if visited[cdx.GetRef()] {
+			continue
 		}
-		return
-	}
-	// handle redirect
-	location := block.HttpHeader().Get("Location")
-	if location == "" {
-		err := errors.New("empty redirect location")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Error().Err(err).Msg("Failed to load resource")
-		return
-	}
+		visited[cdx.GetRef()] = true
 
-	locUrl, err := url.Parse(location)
-	if urlErrors.Type(err) == urlErrors.MissingSchemeNonRelativeURL {
-		locUrl, err = closestAPI.Url().Parse(location)
+		warcRecord, err := h.loadRecord(ctx, cdx.GetRef())
 		if err != nil {
-			err = fmt.Errorf("failed to parse relative location header as URL: %s: %s: %w", warcRecord, location, err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			log.Error().Err(err).Msg("Failed to load resource")
+			writeLoadError(w, err)
+			log.Error().Err(err).Msgf("Failed to load record")
 			return
 		}
-	}
-	if err != nil {
-		err = fmt.Errorf("failed to parse location header as URL: %s: %s: %w", warcRecord, location, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Error().Err(err).Msg("Failed to load resource")
-		return
-	}
-
-	response = make(chan index.CdxResponse)
-	err = h.CdxAPI.Search(ctx, api.ClosestRequest(closest, locUrl), response)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Error().Err(err).Msg("Failed to find closest redirect")
-		return
-	}
-
-	// fields needed to rewrite the location header
-	var sts string
-	var loc string
+		for hops := 0; ; hops++ {
+			location, err := redirectLocation(warcRecord, cdx)
+			if err != nil {
+				_ = warcRecord.Close()
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				log.Error().Err(err).Msg("Failed to load resource")
+				return
+			}
+			if location == nil {
+				defer warcRecord.Close()
+				h.renderCapture(w, r, cdx, warcRecord, decoding)
+				return
+			}
+			_ = warcRecord.Close()
 
-	for res := range response {
-		if res.GetError() != nil {
-			log.Warn().Err(err).Msg("failed result")
-			continue
+			target, err := searchClosest(ctx, h.CdxAPI, api.ClosestRequest(closest, location))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				log.Error().Err(err).Msg("Failed to find closest redirect")
+				return
+			}
+			if target == nil {
+				// captures of redirects are only skipped when redirect handling is configured
+				if !h.Config.handlesRedirects() {
+					http.NotFound(w, r)
+					return
+				}
+				log.Debug().Str("location", location.Href(false)).Msg("Skipping capture of redirect to missing target")
+				continue candidates
+			}
+			if drift := target.GetSts().AsTime().Sub(cdx.GetSts().AsTime()).Abs(); h.Config.MaxRedirectDrift > 0 && drift > h.Config.MaxRedirectDrift {
+				log.Debug().Str("location", location.Href(false)).Msgf("Skipping capture of redirect to target %s away", drift)
+				continue candidates
+			}
+			if h.Config.SkipSelfRedirects && isSelfRedirect(cdx, target) {
+				log.Debug().Str("location", location.Href(false)).Msg("Skipping capture of self-redirect")
+				continue candidates
+			}
+			if hops >= h.Config.MaxRedirectHops {
+				h.renderRedirect(w, r, target)
+				return
+			}
+			// follow the redirect
+			if visited[target.GetRef()] {
+				continue candidates
+			}
+			visited[target.GetRef()] = true
+			cdx = target
+			warcRecord, err = h.loadRecord(ctx, cdx.GetRef())
+			if err != nil {
+				writeLoadError(w, err)
+				log.Error().Err(err).Msgf("Failed to load record")
+				return
+			}
 		}
-		cdx := res.GetCdx()
-		sts = timestamp.TimeTo14(cdx.GetSts().AsTime())
-		loc = cdx.GetUri()
-	}
-	if loc == "" {
-		http.NotFound(w, r)
-		return
-	}
-	before, after, ok := strings.Cut(loc, "?")
-	path := r.URL.Path[:strings.Index(r.URL.Path, "id_")-14] + sts + "id_/" + before
-	scheme := "http"
-	if r.TLS != nil {
-		scheme = "https"
 	}
-	host := r.Host
-	u, err := url.Parse(scheme + "://" + host)
-	if err != nil {
-		err := fmt.Errorf("failed to construct redirect location: %s: %w", loc, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Error().Err(err).Msg("Failed to load resource")
-		return
-	}
-	u.SetPathname(path)
-	if ok {
-		u.SetSearch(after)
+	http.NotFound(w, r)
+}
+
+// setMementoHeaders sets the Memento-Datetime and Link headers (RFC 7089) of the memento cdx.
+func setMementoHeaders(w http.ResponseWriter, r *http.Request, cdx *schema.Cdx, mementoPath string) {
+	uri := cdx.GetUri()
+	links := []string{mementoserver.Link(uri, "rel", "original")}
+	if mementoPath != "" {
+		baseUrl := mementoserver.BaseUrl(r)
+		links = append(links,
+			mementoserver.Link(baseUrl+mementoPath+"/timegate/"+uri, "rel", "timegate"),
+			mementoserver.Link(baseUrl+mementoPath+"/timemap/link/"+uri, "rel", "timemap", "type", "application/link-format"),
+		)
 	}
-	handlers.RenderRedirect(w, u.String())
+	w.Header().Set("Memento-Datetime", mementoserver.Datetime(cdx.GetSts().AsTime()))
+	w.Header().Set("Link", strings.Join(links, ", "))
 }
 
 func isRedirect(code int) bool {
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package warcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/server/handlers"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// HeaderWarcserverCdx is the header of the cdx of the record in pywb's cdxj format.
+	HeaderWarcserverCdx = "Warcserver-Cdx"
+	// HeaderWarcserverSourceColl is the header of the collection of the record.
+	HeaderWarcserverSourceColl = "Warcserver-Source-Coll"
+	// HeaderWarcserverType is the header of the type of the response, always "warc".
+	HeaderWarcserverType = "Warcserver-Type"
+	// HeaderWarcserverPayloadDecoding is the request header of the payload decoding of the
+	// resource endpoint: "none", "transfer" or "content".
+	HeaderWarcserverPayloadDecoding = "Warcserver-Payload-Decoding"
+)
+
+// pywbCdxj returns the cdx as a line of pywb's cdxj format.
+func pywbCdxj(cdx *schema.Cdx) (string, error) {
+	cdxj, err := marshalPywbJson(cdx, nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", cdx.GetSsu(), timestamp.TimeTo14(cdx.GetSts().AsTime()), cdxj), nil
+}
+
+// record writes the full WARC record of the capture of the url parameter closest to the closest
+// parameter, like the resource endpoint of pywb's warcserver. Revisit records are merged with the
+// record they refer to.
+//
+// The body of a POST request is not used for matching.
+func (h Handler) record(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	uri := query.Get(api.ParamUrl)
+	if uri == "" {
+		http.Error(w, fmt.Sprintf("missing required parameter: %s", api.ParamUrl), http.StatusBadRequest)
+		return
+	}
+	closest := query.Get(api.ParamClosest)
+	if closest == "" {
+		closest = timestamp.TimeTo14(time.Now())
+	}
+
+	closestAPI, err := parseClosest(uri, closest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log := log.With().Str("closest", closest).Str("url", uri).Logger()
+
+	limits := h.Config.Limits.Get("warcserver-web")
+	ctx, cancelQuery := limits.Apply(r.Context(), closestAPI)
+	defer cancelQuery()
+
+	response := make(chan index.CdxResponse)
+	err = h.CdxAPI.Search(ctx, closestAPI, response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msgf("Failed to search closest")
+		return
+	}
+
+	// the first result is the closest, the rest are drained
+	var cdx *schema.Cdx
+	var searchErr error
+	for res := range response {
+		if cdx != nil {
+			continue
+		}
+		if err := res.GetError(); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("Failed cdx response")
+			}
+			if searchErr == nil {
+				searchErr = err
+			}
+			// the lookup ends when the limits are exceeded
+			if closestAPI.Truncation(0, err) != "" {
+				cancelQuery()
+			}
+			continue
+		}
+		cdx = res.GetCdx()
+		cancelQuery()
+	}
+	if cdx == nil {
+		if truncated := closestAPI.Truncation(0, searchErr); truncated != "" {
+			w.Header().Set(api.HeaderTruncated, truncated)
+			http.Error(w, searchErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(searchErr, context.Canceled) {
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	cdxj, err := pywbCdxj(cdx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to marshal cdx")
+		return
+	}
+
+	ctx, cancel := limits.Context(r.Context())
+	defer cancel()
+
+	warcRecord, err := h.loadRecord(ctx, cdx.GetRef())
+	if err != nil {
+		writeLoadError(w, err)
+		log.Error().Err(err).Msgf("Failed to load record")
+		return
+	}
+	defer warcRecord.Close()
+
+	setMementoHeaders(w, r, cdx, h.Config.MementoPath)
+	w.Header().Set(HeaderWarcserverCdx, cdxj)
+	w.Header().Set(HeaderWarcserverSourceColl, h.Config.Collection)
+	w.Header().Set(HeaderWarcserverType, "warc")
+	w.Header().Set("Content-Type", "application/warc-record")
+
+	if _, err := handlers.RenderRecord(w, warcRecord); err != nil {
+		log.Warn().Err(err).Msg("Failed to write record")
+	}
+}
@@ -0,0 +1,82 @@
+package warcserver_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/client/clienttest"
+	"github.com/nlnwa/gowarcserver/server/warcserver"
+)
+
+func TestRecord(t *testing.T) {
+	server := clienttest.NewServer(t, clienttest.WithFiles("../../testdata/example.warc.gz"))
+
+	tests := []struct {
+		name    string
+		values  url.Values
+		status  int
+		id      string
+		closest string
+	}{
+		{
+			name:    "response",
+			values:  url.Values{"url": {"http://example.com/"}, "closest": {"20170306040206"}},
+			status:  http.StatusOK,
+			id:      "urn:uuid:a9c51e3e-0221-11e7-bf66-0242ac120005",
+			closest: "20170306040206",
+		},
+		{
+			name:    "revisit is merged",
+			values:  url.Values{"url": {"http://example.com/"}, "closest": {"20170306040348"}},
+			status:  http.StatusOK,
+			id:      "urn:uuid:e6e395ca-0221-11e7-a18d-0242ac120005",
+			closest: "20170306040348",
+		},
+		{
+			name:   "missing url",
+			values: url.Values{"closest": {"20170306040206"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not found",
+			values: url.Values{"url": {"http://example.org/"}, "closest": {"20170306040206"}},
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(server.URL + "/warcserver/resource?" + tt.values.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", res.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := res.Header.Get(warcserver.HeaderWarcserverType); got != "warc" {
+				t.Errorf("got %s %q, want %q", warcserver.HeaderWarcserverType, got, "warc")
+			}
+			if got := res.Header.Get(warcserver.HeaderWarcserverCdx); !strings.HasPrefix(got, "com,example,//:http:/ "+tt.closest+" {") {
+				t.Errorf("got %s %q", warcserver.HeaderWarcserverCdx, got)
+			}
+			rec, _, _, err := gowarc.NewUnmarshaler().Unmarshal(bufio.NewReader(res.Body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rec.Close()
+			if rec.Type() != gowarc.Response {
+				t.Errorf("got record type %s, want %s", rec.Type(), gowarc.Response)
+			}
+			if got := rec.WarcHeader().GetId(gowarc.WarcRecordID); got != tt.id {
+				t.Errorf("got record %s, want %s", got, tt.id)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package warcserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/handlers"
+	"github.com/nlnwa/gowarcserver/surt"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	urlErrors "github.com/nlnwa/whatwg-url/errors"
+	"github.com/nlnwa/whatwg-url/url"
+	"github.com/rs/zerolog/log"
+)
+
+// redirectLocation returns the location of the record of the capture if it is a redirect, or nil if it is not.
+func redirectLocation(warcRecord gowarc.WarcRecord, cdx *schema.Cdx) (*url.Url, error) {
+	block, ok := warcRecord.Block().(gowarc.HttpResponseBlock)
+	if !ok || warcRecord.Type() == gowarc.Resource || !isRedirect(block.HttpStatusCode()) {
+		return nil, nil
+	}
+	location := block.HttpHeader().Get("Location")
+	if location == "" {
+		return nil, errors.New("empty redirect location")
+	}
+
+	locUrl, err := url.Parse(location)
+	if urlErrors.Type(err) == urlErrors.MissingSchemeNonRelativeURL {
+		var base *url.Url
+		base, err = url.Parse(cdx.GetUri())
+		if err == nil {
+			locUrl, err = base.Parse(location)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse relative location header as URL: %s: %s: %w", warcRecord, location, err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse location header as URL: %s: %s: %w", warcRecord, location, err)
+	}
+	return locUrl, nil
+}
+
+// selfRedirectKey returns the SURT of the url without scheme and trailing slash of the path.
+func selfRedirectKey(u string) string {
+	s, err := surt.SurtS(u, false)
+	if err != nil {
+		return u
+	}
+	path, query, _ := strings.Cut(s, "?")
+	return strings.TrimSuffix(path, "/") + "?" + query
+}
+
+// isSelfRedirect reports whether the capture of a redirect points back to itself, i.e. the
+// closest capture of the target is of the same SURT, ignoring the scheme and a trailing slash,
+// at the same timestamp.
+func isSelfRedirect(cdx *schema.Cdx, target *schema.Cdx) bool {
+	return selfRedirectKey(cdx.GetUri()) == selfRedirectKey(target.GetUri()) &&
+		timestamp.TimeTo14(cdx.GetSts().AsTime()) == timestamp.TimeTo14(target.GetSts().AsTime())
+}
+
+// renderRedirect redirects the request to the resource endpoint of the target capture.
+func (h Handler) renderRedirect(w http.ResponseWriter, r *http.Request, target *schema.Cdx) {
+	// fields needed to rewrite the location header
+	sts := timestamp.TimeTo14(target.GetSts().AsTime())
+	loc := target.GetUri()
+
+	// the request path is the path of the route up to the timestamp, the timestamp and the url
+	params := httprouter.ParamsFromContext(r.Context())
+	routePath := strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, params.ByName("url")), params.ByName("timestamp"))
+
+	before, after, ok := strings.Cut(loc, "?")
+	path := routePath + sts + "id_/" + before
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	u, err := url.Parse(scheme + "://" + host)
+	if err != nil {
+		err := fmt.Errorf("failed to construct redirect location: %s: %w", loc, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load resource")
+		return
+	}
+	u.SetPathname(path)
+	if ok {
+		u.SetSearch(after)
+	}
+	handlers.RenderRedirect(w, u.String())
+}
@@ -0,0 +1,233 @@
+package warcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type capture struct {
+	uri      string
+	ts       string
+	status   int
+	location string
+	body     string
+	// chunked makes the payload chunked transfer encoded
+	chunked bool
+	// ple is the payload length of the cdx if not zero, otherwise the length of the body
+	ple int
+}
+
+// fakeArchive is a CdxAPI and WarcLoader of captures where the storage ref of a capture is its index.
+type fakeArchive []capture
+
+func (f fakeArchive) cdx(i int) *schema.Cdx {
+	c := f[i]
+	t, _ := timestamp.Parse(c.ts)
+	ple := c.ple
+	if ple == 0 {
+		ple = len(c.body)
+	}
+	return &schema.Cdx{
+		Uri: c.uri,
+		Sts: timestamppb.New(t),
+		Hsc: int32(c.status),
+		Srt: "response",
+		Ref: "warcfile:archive#" + strconv.Itoa(i),
+		Ple: int64(ple),
+	}
+}
+
+func (f fakeArchive) Search(ctx context.Context, req index.Request, res chan<- index.CdxResponse) error {
+	cdxs := make(indextest.CdxAPI, len(f))
+	for i := range f {
+		cdxs[i] = f.cdx(i)
+	}
+	return cdxs.Search(ctx, req, res)
+}
+
+func (f fakeArchive) LoadById(context.Context, string) (gowarc.WarcRecord, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f fakeArchive) LoadByStorageRef(_ context.Context, ref string) (gowarc.WarcRecord, error) {
+	i, err := strconv.Atoi(ref[strings.LastIndexByte(ref, '#')+1:])
+	if err != nil {
+		return nil, err
+	}
+	c := f[i]
+	t, _ := timestamp.Parse(c.ts)
+	builder := gowarc.NewRecordBuilder(gowarc.Response, gowarc.WithAddMissingDigest(true), gowarc.WithAddMissingContentLength(true))
+	_, _ = fmt.Fprintf(builder, "HTTP/1.1 %d %s\r\n", c.status, http.StatusText(c.status))
+	if c.location != "" {
+		_, _ = fmt.Fprintf(builder, "Location: %s\r\n", c.location)
+	}
+	if c.chunked {
+		_, _ = fmt.Fprintf(builder, "Transfer-Encoding: chunked\r\n\r\n%x\r\n%s\r\n0\r\n\r\n", len(c.body), c.body)
+	} else {
+		_, _ = fmt.Fprintf(builder, "Content-Length: %d\r\n\r\n%s", len(c.body), c.body)
+	}
+	builder.AddWarcHeader(gowarc.WarcRecordID, fmt.Sprintf("<urn:uuid:%d>", i))
+	builder.AddWarcHeader(gowarc.WarcDate, t.Format(time.RFC3339))
+	builder.AddWarcHeader(gowarc.WarcTargetURI, c.uri)
+	builder.AddWarcHeader(gowarc.ContentType, gowarc.ApplicationHttp+";msgtype=response")
+	record, _, err := builder.Build()
+	return record, err
+}
+
+func TestResourceRedirect(t *testing.T) {
+	archive := fakeArchive{
+		// self-redirects at the same timestamp
+		{uri: "http://example.com/", ts: "20200101000000", status: 301, location: "https://example.com/"},
+		{uri: "https://example.com/", ts: "20200101000000", status: 301, location: "http://example.com/"},
+		{uri: "http://example.com/", ts: "20190101000000", status: 200, body: "old"},
+		// a chain of redirects
+		{uri: "http://example.org/a", ts: "20200101000000", status: 302, location: "/b"},
+		{uri: "http://example.org/b", ts: "20200101000000", status: 302, location: "/c"},
+		{uri: "http://example.org/c", ts: "20200101000001", status: 200, body: "c"},
+		// a redirect to a target that is not archived, and an older capture
+		{uri: "http://example.net/", ts: "20200101000000", status: 302, location: "/missing"},
+		{uri: "http://example.net/", ts: "20190101000000", status: 200, body: "net"},
+		// a redirect to a target captured much later
+		{uri: "http://example.org/d", ts: "20200101000000", status: 302, location: "/e"},
+		{uri: "http://example.org/e", ts: "20200301000000", status: 200, body: "e"},
+		// a self-redirect to a capture that is not a redirect
+		{uri: "http://example.edu/", ts: "20200101000000", status: 301, location: "https://example.edu/"},
+		{uri: "https://example.edu/", ts: "20200101000000", status: 200, body: "https"},
+		{uri: "http://example.edu/", ts: "20190101000000", status: 200, body: "edu"},
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		// timestamp is the timestamp of the request, or 20200101000000 if empty
+		timestamp string
+		config    Config
+		status    int
+		location  string
+		body      string
+	}{
+		{
+			name:     "redirect",
+			url:      "http://example.com/",
+			status:   http.StatusFound,
+			location: "/web/20200101000000id_/https://example.com/",
+		},
+		{
+			name:      "redirect with short timestamp",
+			url:       "http://example.com/",
+			timestamp: "2020",
+			status:    http.StatusFound,
+			location:  "/web/20200101000000id_/https://example.com/",
+		},
+		{
+			name:   "follow self-redirect",
+			url:    "http://example.edu/",
+			config: Config{MaxRedirectHops: 1},
+			status: http.StatusOK,
+			body:   "https",
+		},
+		{
+			name:   "skip self-redirect when following redirects",
+			url:    "http://example.edu/",
+			config: Config{SkipSelfRedirects: true, MaxRedirectHops: 1},
+			status: http.StatusOK,
+			body:   "edu",
+		},
+		{
+			name:   "skip self-redirect",
+			url:    "http://example.com/",
+			config: Config{SkipSelfRedirects: true},
+			status: http.StatusOK,
+			body:   "old",
+		},
+		{
+			name:   "follow redirects",
+			url:    "http://example.org/a",
+			config: Config{MaxRedirectHops: 2},
+			status: http.StatusOK,
+			body:   "c",
+		},
+		{
+			name:     "follow too few redirects",
+			url:      "http://example.org/a",
+			config:   Config{MaxRedirectHops: 1},
+			status:   http.StatusFound,
+			location: "/web/20200101000001id_/http://example.org/c",
+		},
+		{
+			name:   "follow circular redirects",
+			url:    "http://example.com/",
+			config: Config{MaxRedirectHops: 5},
+			status: http.StatusOK,
+			body:   "old",
+		},
+		{
+			name:     "drift within limit",
+			url:      "http://example.org/d",
+			config:   Config{MaxRedirectDrift: 100 * 24 * time.Hour},
+			status:   http.StatusFound,
+			location: "/web/20200301000000id_/http://example.org/e",
+		},
+		{
+			name:   "missing target",
+			url:    "http://example.net/",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "skip missing target",
+			url:    "http://example.net/",
+			config: Config{SkipSelfRedirects: true},
+			status: http.StatusOK,
+			body:   "net",
+		},
+		{
+			name:   "drift over limit",
+			url:    "http://example.org/d",
+			config: Config{MaxRedirectDrift: 24 * time.Hour},
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			config := tt.config
+			Register(Handler{CdxAPI: archive, WarcLoader: archive, Config: &config}, router, func(h http.Handler) http.Handler { return h }, "")
+
+			ts := tt.timestamp
+			if ts == "" {
+				ts = "20200101000000"
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/web/"+ts+"id_/"+tt.url, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.location != "" {
+				if got := rec.Header().Get("Location"); got != "http://localhost"+tt.location {
+					t.Errorf("got location %q, want %q", got, "http://localhost"+tt.location)
+				}
+			}
+			if tt.body != "" {
+				if got, _ := io.ReadAll(rec.Body); string(got) != tt.body {
+					t.Errorf("got body %q, want %q", got, tt.body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package warcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nlnwa/gowarc"
+	"github.com/nlnwa/gowarcserver/loader"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/handlers"
+	"github.com/rs/zerolog/log"
+)
+
+// loadRecord loads the warc record by storage ref. Revisit records the loader fails to resolve
+// are resolved by the closest capture of the target URI.
+func (h Handler) loadRecord(ctx context.Context, ref string) (gowarc.WarcRecord, error) {
+	var warcRecord gowarc.WarcRecord
+	var err error
+	retry := true
+	for warcRecord == nil {
+		warcRecord, err = h.WarcLoader.LoadByStorageRef(ctx, ref)
+		var errResolveRevisit loader.ErrResolveRevisit
+		if errors.As(err, &errResolveRevisit) && retry {
+			ref, err = RevisitResolver{CdxAPI: h.CdxAPI}.ResolveRevisit(ctx, errResolveRevisit.TargetURI, errResolveRevisit.Date)
+			if err == nil && ref == "" {
+				err = errResolveRevisit
+			}
+			retry = false
+		}
+		if err != nil {
+			if warcRecord != nil {
+				_ = warcRecord.Close()
+			}
+			return nil, err
+		}
+	}
+	return warcRecord, nil
+}
+
+// writeLoadError writes the error of loading a record.
+func writeLoadError(w http.ResponseWriter, err error) {
+	var errWarcRefersToNotFound loader.ErrWarcRefersToNotFound
+	var errResolveRevisit loader.ErrResolveRevisit
+	if errors.As(err, &errWarcRefersToNotFound) || errors.As(err, &errResolveRevisit) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// payloadDecoding returns the payload decoding of the Warcserver-Payload-Decoding header of
+// the request, or the configured payload decoding if the header is not set.
+func (h Handler) payloadDecoding(r *http.Request) (loader.PayloadDecoding, error) {
+	if v := r.Header.Get(HeaderWarcserverPayloadDecoding); v != "" {
+		return loader.ParsePayloadDecoding(v)
+	}
+	return h.Config.PayloadDecoding, nil
+}
+
+// renderCapture renders the payload of the record of the capture decoded according to decoding.
+func (h Handler) renderCapture(w http.ResponseWriter, r *http.Request, cdx *schema.Cdx, warcRecord gowarc.WarcRecord, decoding loader.PayloadDecoding) {
+	setMementoHeaders(w, r, cdx, h.Config.MementoPath)
+
+	// resource records have no HTTP headers so we render the block as payload
+	if warcRecord.Type() == gowarc.Resource {
+		p, err := warcRecord.Block().RawBytes()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			log.Error().Err(err).Msg("Failed to load resource")
+			return
+		}
+		header := http.Header{}
+		header.Set("Content-Type", warcRecord.WarcHeader().Get(gowarc.ContentType))
+		header.Set("Content-Length", warcRecord.WarcHeader().Get(gowarc.ContentLength))
+		size, err := warcRecord.WarcHeader().GetInt64(gowarc.ContentLength)
+		if err != nil {
+			size = -1
+		}
+		if sr, ok := warcRecord.(loader.SeekableRecord); ok {
+			p = sr.BlockSection()
+		}
+		err = handlers.RenderRange(w, r, header, http.StatusOK, p, size)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to load resource")
+		}
+		return
+	}
+
+	block, ok := warcRecord.Block().(gowarc.HttpResponseBlock)
+	if !ok {
+		err := fmt.Errorf("record not renderable: %s", warcRecord)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load resource")
+		return
+	}
+
+	p, err := block.PayloadBytes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load resource")
+		return
+	}
+	// the header as captured, as the header parsed by the block has no Transfer-Encoding
+	header, err := loader.HttpHeader(block)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to load resource")
+		return
+	}
+	size := payloadSize(warcRecord, block, header)
+	// the payload of uncompressed records is read by seeking, unless it is transfer encoded
+	if sr, ok := warcRecord.(loader.SeekableRecord); ok && header.Get("Transfer-Encoding") == "" {
+		section := sr.BlockSection()
+		n := int64(len(block.ProtocolHeaderBytes()))
+		p = io.NewSectionReader(section, n, section.Size()-n)
+		size = section.Size() - n
+	}
+	p, decoded, err := loader.DecodePayload(header, p, decoding)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msg("Failed to decode payload")
+		return
+	}
+	// the size of decoded payloads is not known
+	if decoded {
+		size = -1
+	}
+	err = handlers.RenderRange(w, r, header, block.HttpStatusCode(), p, size)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load resource")
+	}
+}
+
+// payloadSize returns the size of the payload of the HTTP message of block with header,
+// or -1 if it is not known.
+//
+// The size is the size of the block as stored, i.e. the Content-Length of the WARC
+// record, minus the size of the HTTP header, unless the payload has a transfer encoding.
+func payloadSize(warcRecord gowarc.WarcRecord, block gowarc.HttpResponseBlock, header http.Header) int64 {
+	if header.Get("Transfer-Encoding") != "" {
+		return -1
+	}
+	blockSize, err := warcRecord.WarcHeader().GetInt64(gowarc.ContentLength)
+	if err != nil {
+		return -1
+	}
+	size := blockSize - int64(len(block.ProtocolHeaderBytes()))
+	if size < 0 {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,90 @@
+package warcserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/loader"
+)
+
+func TestResourcePayloadDecoding(t *testing.T) {
+	archive := fakeArchive{
+		{uri: "http://example.com/", ts: "20200101000000", status: 200, body: "hello", chunked: true},
+	}
+	tests := []struct {
+		name     string
+		config   Config
+		decoding string
+		status   int
+		body     string
+	}{
+		{name: "as captured", status: http.StatusOK, body: "5\r\nhello\r\n0\r\n\r\n"},
+		{name: "configured", config: Config{PayloadDecoding: loader.DecodeTransfer}, status: http.StatusOK, body: "hello"},
+		{name: "requested", decoding: "transfer", status: http.StatusOK, body: "hello"},
+		{name: "requested as captured", config: Config{PayloadDecoding: loader.DecodeTransfer}, decoding: "none", status: http.StatusOK, body: "5\r\nhello\r\n0\r\n\r\n"},
+		{name: "invalid", decoding: "zip", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			config := tt.config
+			Register(Handler{CdxAPI: archive, WarcLoader: archive, Config: &config}, router, func(h http.Handler) http.Handler { return h }, "")
+
+			req := httptest.NewRequest("GET", "http://localhost/web/20200101000000id_/http://example.com/", nil)
+			if tt.decoding != "" {
+				req.Header.Set(HeaderWarcserverPayloadDecoding, tt.decoding)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" {
+				if got, _ := io.ReadAll(rec.Body); string(got) != tt.body {
+					t.Errorf("got body %q, want %q", got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceRange(t *testing.T) {
+	archive := fakeArchive{
+		// the payload length of the cdx does not match the payload
+		{uri: "http://example.com/", ts: "20200101000000", status: 200, body: "hello world", ple: 100},
+		{uri: "http://example.org/", ts: "20200101000000", status: 200, body: "hello world", chunked: true},
+	}
+	tests := []struct {
+		name         string
+		url          string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{name: "range", url: "http://example.com/", status: http.StatusPartialContent, contentRange: "bytes 6-10/11", body: "world"},
+		{name: "unknown size", url: "http://example.org/", status: http.StatusOK, body: "b\r\nhello world\r\n0\r\n\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := httprouter.New()
+			Register(Handler{CdxAPI: archive, WarcLoader: archive, Config: &Config{}}, router, func(h http.Handler) http.Handler { return h }, "")
+
+			req := httptest.NewRequest("GET", "http://localhost/web/20200101000000id_/"+tt.url, nil)
+			req.Header.Set("Range", "bytes=6-")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("got Content-Range %q, want %q", got, tt.contentRange)
+			}
+			if got, _ := io.ReadAll(rec.Body); string(got) != tt.body {
+				t.Errorf("got body %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
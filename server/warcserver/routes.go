@@ -26,4 +26,7 @@ func Register(h Handler, r *httprouter.Router, mw func(http.Handler) http.Handle
 	// https://pywb.readthedocs.io/en/latest/manual/warcserver.html#warcserver-api
 	r.Handler("GET", pathPrefix+"/cdx", mw(http.HandlerFunc(h.index)))
 	r.Handler("GET", pathPrefix+"/web/:timestamp/*url", mw(http.HandlerFunc(h.resource)))
+	// full WARC records for use of gowarcserver as a remote warcserver of pywb
+	r.Handler("GET", pathPrefix+"/resource", mw(http.HandlerFunc(h.record)))
+	r.Handler("POST", pathPrefix+"/resource/postreq", mw(http.HandlerFunc(h.record)))
 }
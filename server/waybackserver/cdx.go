@@ -0,0 +1,384 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package waybackserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/internal/keyvalue"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultPageSize is the number of captures of a page when the page size is not given.
+const DefaultPageSize = 1000
+
+// MaxPagedCaptures is the number of captures that can be paged through. It bounds the
+// captures skipped to reach a page and the captures counted by showNumPages.
+const MaxPagedCaptures = 100000
+
+// cdxFields are the fields of the CDX server API in their default order.
+var cdxFields = []string{"urlkey", "timestamp", "original", "mimetype", "statuscode", "digest", "length"}
+
+// cdxFieldMap maps the fields of the CDX server API to the cdx fields that captures are collapsed on.
+var cdxFieldMap = map[string]string{
+	"urlkey":     "ssu",
+	"timestamp":  "sts",
+	"original":   "uri",
+	"mimetype":   "mct",
+	"statuscode": "hsc",
+	"digest":     "dig",
+	"length":     "rle",
+}
+
+// cdxFieldValues returns the values of the fields of the CDX server API. Filters
+// match the same values that are written.
+var cdxFieldValues = map[string]func(*schema.Cdx) string{
+	"urlkey":    api.UrlKey,
+	"timestamp": func(cdx *schema.Cdx) string { return timestamp.TimeTo14(cdx.GetSts().AsTime()) },
+	"original":  (*schema.Cdx).GetUri,
+	"mimetype":  func(cdx *schema.Cdx) string { return api.MediaType(cdx.GetMct()) },
+	"statuscode": func(cdx *schema.Cdx) string {
+		if cdx.GetHsc() == 0 {
+			return "-"
+		}
+		return strconv.Itoa(int(cdx.GetHsc()))
+	},
+	"digest": (*schema.Cdx).GetDig,
+	"length": func(cdx *schema.Cdx) string { return strconv.FormatInt(cdx.GetRle(), 10) },
+}
+
+// cdxRequest is a request of the CDX server API.
+type cdxRequest struct {
+	search        *api.SearchRequest
+	fields        []string
+	json          bool
+	showResumeKey bool
+	// lastN is true if the limit was negative, i.e. the last captures are requested
+	lastN        bool
+	paged        bool
+	page         int
+	pageSize     int
+	showNumPages bool
+}
+
+// parseCdxRequest translates the parameters of the CDX server API to a search request.
+//
+// See https://github.com/internetarchive/wayback/tree/master/wayback-cdx-server
+func parseCdxRequest(values url.Values) (*cdxRequest, error) {
+	req := &cdxRequest{
+		fields:        cdxFields,
+		json:          values.Get("output") == "json",
+		showResumeKey: values.Get("showResumeKey") == "true",
+		showNumPages:  values.Get("showNumPages") == "true",
+		pageSize:      DefaultPageSize,
+	}
+	if fl := values.Get("fl"); fl != "" {
+		req.fields = strings.Split(fl, ",")
+		for _, field := range req.fields {
+			if _, ok := cdxFieldValues[field]; !ok {
+				return nil, fmt.Errorf("invalid fl: unknown field: %s", field)
+			}
+		}
+	}
+
+	search := url.Values{}
+	for _, param := range []string{api.ParamUrl, api.ParamFrom, api.ParamTo, api.ParamClosest, api.ParamCollapse, api.ParamResumeKey, api.ParamMatchType} {
+		if v := values.Get(param); v != "" {
+			search.Set(param, v)
+		}
+	}
+	if sort := values.Get(api.ParamSort); sort != "" {
+		search.Set(api.ParamSort, sort)
+	}
+
+	if search.Get(api.ParamUrl) == "" {
+		return nil, errors.New("missing url")
+	}
+
+	// filters are regular expressions that must match the whole field value
+	for _, filter := range values[api.ParamFilter] {
+		not := strings.HasPrefix(filter, "!")
+		filter = strings.TrimPrefix(filter, "!")
+		field, value, ok := strings.Cut(filter, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter: %s", filter)
+		}
+		if _, ok := cdxFieldValues[field]; !ok {
+			return nil, fmt.Errorf("invalid filter: unknown field: %s", field)
+		}
+		// an empty alternative ("||") would separate the terms of the filter
+		for strings.Contains(value, "||") {
+			value = strings.ReplaceAll(value, "||", "|(?:)|")
+		}
+		f := "~" + field + ":^(?:" + value + ")$"
+		if not {
+			f = "!" + f
+		}
+		search.Add(api.ParamFilter, f)
+	}
+
+	if limit := values.Get(api.ParamLimit); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("limit must be an integer, was: %s", limit)
+		}
+		if n < 0 {
+			// the last captures are the first captures in reverse
+			n = -n
+			req.lastN = true
+			search.Set(api.ParamSort, api.SortReverse)
+		}
+		search.Set(api.ParamLimit, strconv.Itoa(n))
+	}
+
+	if pageSize := values.Get("pageSize"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("pageSize must be a positive integer, was: %s", pageSize)
+		}
+		req.pageSize = n
+	}
+	if page := values.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("page must be a non-negative integer, was: %s", page)
+		}
+		req.page = n
+		req.paged = true
+	}
+	if req.paged || req.showNumPages {
+		if req.lastN || search.Get(api.ParamResumeKey) != "" {
+			return nil, errors.New("paging can not be combined with a negative limit or a resume key")
+		}
+		search.Del(api.ParamLimit)
+	}
+	if req.paged {
+		if req.pageSize > MaxPagedCaptures || req.page >= MaxPagedCaptures/req.pageSize {
+			return nil, fmt.Errorf("pages beyond the first %d captures are not available", MaxPagedCaptures)
+		}
+		search.Set(api.ParamLimit, strconv.Itoa((req.page+1)*req.pageSize))
+	}
+	if req.showNumPages {
+		search.Set(api.ParamLimit, strconv.Itoa(MaxPagedCaptures))
+	}
+
+	req.search = &api.SearchRequest{FilterMap: cdxFieldMap, FilterFields: cdxFieldValues}
+	if err := req.search.Parse(search); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// cdxRowWriter writes the rows of a CDX server API response.
+type cdxRowWriter interface {
+	Write(row []string) error
+	// Close writes the end of the response with the resume key if not empty.
+	Close(resumeKey string) error
+}
+
+// textRowWriter writes rows as space separated lines where whitespace in values is percent-encoded.
+type textRowWriter struct {
+	w io.Writer
+}
+
+func (t textRowWriter) Write(row []string) error {
+	values := make([]string, len(row))
+	for i, v := range row {
+		values[i] = api.TextValue(v)
+	}
+	_, err := io.WriteString(t.w, strings.Join(values, " ")+"\n")
+	return err
+}
+
+func (t textRowWriter) Close(resumeKey string) error {
+	if resumeKey == "" {
+		return nil
+	}
+	_, err := io.WriteString(t.w, "\n"+resumeKey+"\n")
+	return err
+}
+
+// jsonRowWriter writes rows as a json array of arrays where the first row is the field names.
+type jsonRowWriter struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
+
+func (j *jsonRowWriter) Write(row []string) error {
+	if j.count == 0 {
+		if err := j.write("[", j.fields); err != nil {
+			return err
+		}
+	}
+	j.count++
+	return j.write(",\n", row)
+}
+
+func (j *jsonRowWriter) write(sep string, row []string) error {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(j.w, sep+string(b))
+	return err
+}
+
+func (j *jsonRowWriter) Close(resumeKey string) error {
+	if j.count == 0 {
+		// there are no results
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	if resumeKey != "" {
+		if err := j.write(",\n", []string{}); err != nil {
+			return err
+		}
+		if err := j.write(",\n", []string{resumeKey}); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
+
+// cdx implements the CDX server API.
+func (h Handler) cdx(w http.ResponseWriter, r *http.Request) {
+	req, err := parseCdxRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	count := 0
+	defer func() {
+		log.Debug().Str("request", fmt.Sprintf("%+v", req.search)).Msgf("Found %d items in %s", count, time.Since(start))
+	}()
+
+	ctx, cancel := h.Config.Limits.Get("wayback-cdx").Apply(r.Context(), req.search)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req.search, response); err != nil {
+		if errors.Is(err, index.InvalidResumeKeyError) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error().Err(err).Msgf("Search failed: %+v", req.search)
+		return
+	}
+
+	var rw cdxRowWriter = textRowWriter{w: w}
+	if req.json && !req.showNumPages {
+		w.Header().Set("Content-Type", "application/json")
+		rw = &jsonRowWriter{w: w, fields: req.fields}
+	} else {
+		w.Header().Set("Content-Type", "text/plain")
+	}
+	// the reason of truncation is only known when all results are written
+	w.Header().Set("Trailer", api.HeaderTruncated)
+	write := func(cdx *schema.Cdx) error {
+		row := make([]string, len(req.fields))
+		for i, field := range req.fields {
+			row[i] = cdxFieldValues[field](cdx)
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		return rw.Write(row)
+	}
+
+	// captures of previous pages are skipped
+	skip := 0
+	if req.paged {
+		skip = req.page * req.pageSize
+	}
+	// the last captures are buffered and written in reverse order
+	var lastN []*schema.Cdx
+	var last *schema.Cdx
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Warn().Err(err).Msg("failed result")
+			searchErr = err
+			continue
+		}
+		count++
+		if req.showNumPages || count <= skip {
+			continue
+		}
+		last = res.GetCdx()
+		if req.lastN {
+			lastN = append(lastN, last)
+			continue
+		}
+		if err := write(last); err != nil {
+			log.Warn().Err(err).Msg("failed to write result")
+			return
+		}
+	}
+	truncated := req.search.Truncation(count, searchErr)
+	if req.showNumPages {
+		// the number of pages is a lower bound if the count is truncated
+		if truncated == "" && count >= MaxPagedCaptures {
+			truncated = api.TruncatedMaxResults
+		}
+		if truncated != "" {
+			w.Header().Set(api.HeaderTruncated, truncated)
+		}
+		_, _ = fmt.Fprintln(w, (count+req.pageSize-1)/req.pageSize)
+		return
+	}
+	for i := len(lastN) - 1; i >= 0; i-- {
+		if err := write(lastN[i]); err != nil {
+			log.Warn().Err(err).Msg("failed to write result")
+			return
+		}
+	}
+
+	// there may be more results if the limit was reached
+	var resumeKey string
+	limit := req.search.Limit()
+	if req.showResumeKey && !req.paged && !req.lastN && last != nil && limit > 0 && count >= limit {
+		resumeKey = api.EncodeResumeKey(keyvalue.CdxKeyOf(last).String())
+	}
+	if err := rw.Close(resumeKey); err != nil {
+		log.Warn().Err(err).Msg("failed to write result")
+		return
+	}
+	if truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+	}
+}
@@ -0,0 +1,208 @@
+package waybackserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/schema"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newCdxTestServer() *httprouter.Router {
+	newCdx := func(uri string, ssu string, t string, hsc int32, dig string) *schema.Cdx {
+		ts, _ := time.Parse("20060102150405", t)
+		return &schema.Cdx{Uri: uri, Ssu: ssu, Sts: timestamppb.New(ts), Hsc: hsc, Dig: dig, Mct: "text/html", Rle: 100, Srt: "response"}
+	}
+	charset := newCdx("http://example.com/a", "com,example,//:http:/a", "20200301000000", 200, "C")
+	charset.Mct = "text/html; charset=utf-8"
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{
+			newCdx("http://example.com/", "com,example,//:http:/", "20200101000000", 200, "A"),
+			newCdx("http://example.com/", "com,example,//:http:/", "20200102000000", 200, "A"),
+			newCdx("http://example.com/", "com,example,//:http:/", "20200201000000", 404, "B"),
+			charset,
+			newCdx("http://www.example.com/", "com,example,www,//:http:/", "20200401000000", 200, "D"),
+		},
+		Config: &Config{},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+	return router
+}
+
+func TestCdx(t *testing.T) {
+	tests := []struct {
+		query  string
+		status int
+		want   string
+	}{
+		{
+			query:  "url=example.com/&fl=timestamp,statuscode",
+			status: http.StatusOK,
+			want:   "20200101000000 200\n20200102000000 200\n20200201000000 404\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&output=json&limit=1",
+			status: http.StatusOK,
+			want:   "[[\"timestamp\"],\n[\"20200101000000\"]]\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&output=json&filter=statuscode:3..",
+			status: http.StatusOK,
+			want:   "[]\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&filter=!statuscode:200",
+			status: http.StatusOK,
+			want:   "20200201000000\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&filter=mimetype:image/png||text/html",
+			status: http.StatusOK,
+			want:   "20200101000000\n20200102000000\n20200201000000\n",
+		},
+		{
+			query:  "url=example.com/a&fl=timestamp&filter=mimetype:text/html",
+			status: http.StatusOK,
+			want:   "20200301000000\n",
+		},
+		{
+			query:  "url=example.com/*&fl=timestamp&filter=urlkey:com,example%5C)/a",
+			status: http.StatusOK,
+			want:   "20200301000000\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp,digest&collapse=digest",
+			status: http.StatusOK,
+			want:   "20200101000000 A\n20200201000000 B\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&collapse=timestamp:6",
+			status: http.StatusOK,
+			want:   "20200101000000\n20200201000000\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&limit=-2",
+			status: http.StatusOK,
+			want:   "20200102000000\n20200201000000\n",
+		},
+		{
+			query:  "url=example.com/*&fl=original",
+			status: http.StatusOK,
+			want:   "http://example.com/\nhttp://example.com/\nhttp://example.com/\nhttp://example.com/a\n",
+		},
+		{
+			query:  "url=*.example.com&fl=original&collapse=urlkey",
+			status: http.StatusOK,
+			want:   "http://example.com/\nhttp://example.com/a\nhttp://www.example.com/\n",
+		},
+		{
+			query:  "url=www.example.com/&fl=urlkey,original",
+			status: http.StatusOK,
+			want:   "com,example)/ http://www.example.com/\n",
+		},
+		{
+			query:  "url=example.com/a&fl=urlkey,mimetype",
+			status: http.StatusOK,
+			want:   "com,example)/a text/html\n",
+		},
+		{
+			query:  "url=example.com/a&fl=urlkey,mimetype&output=json",
+			status: http.StatusOK,
+			want:   "[[\"urlkey\",\"mimetype\"],\n[\"com,example)/a\",\"text/html\"]]\n",
+		},
+		{
+			query:  "url=example.com/&fl=timestamp&page=1&pageSize=2",
+			status: http.StatusOK,
+			want:   "20200201000000\n",
+		},
+		{
+			query:  "url=example.com/&showNumPages=true&pageSize=2",
+			status: http.StatusOK,
+			want:   "2\n",
+		},
+		{
+			query:  "url=example.com/&page=100000&pageSize=1",
+			status: http.StatusBadRequest,
+		},
+		{
+			query:  "url=example.com/&page=0&pageSize=100001",
+			status: http.StatusBadRequest,
+		},
+		{
+			query:  "url=example.com/&fl=nope",
+			status: http.StatusBadRequest,
+		},
+		{
+			query:  "url=example.com/&filter=nope:200",
+			status: http.StatusBadRequest,
+		},
+		{
+			query:  "fl=timestamp",
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newCdxTestServer().ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/cdx/search/cdx?"+test.query, nil))
+			if rec.Code != test.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, test.status, rec.Body.String())
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			if got := rec.Body.String(); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCdxResumeKey(t *testing.T) {
+	router := newCdxTestServer()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/cdx/search/cdx?url=example.com/&fl=timestamp&limit=2&showResumeKey=true&output=json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	want := "[[\"timestamp\"],\n[\"20200101000000\"],\n[\"20200102000000\"],\n[],\n[\"Y29tLGV4YW1wbGUsLyAyMDIwMDEwMjAwMDAwMCA6aHR0cDogcmVzcG9uc2U\"]]\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCdxLimits(t *testing.T) {
+	ts, _ := time.Parse("20060102150405", "20200101000000")
+	cdx := &schema.Cdx{Uri: "http://example.com/", Ssu: "com,example,//:http:/", Sts: timestamppb.New(ts), Srt: "response"}
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{cdx, cdx, cdx},
+		Config: &Config{Limits: api.QueryLimits{"wayback-cdx": {MaxResults: 2}}},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "url=example.com/&fl=timestamp", want: "20200101000000\n20200101000000\n"},
+		{query: "url=example.com/&showNumPages=true&pageSize=1", want: "2\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/cdx/search/cdx?"+test.query, nil))
+			if got := rec.Body.String(); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if got := rec.Result().Trailer.Get(api.HeaderTruncated); got != api.TruncatedMaxResults {
+				t.Errorf("got truncated %q, want %q", got, api.TruncatedMaxResults)
+			}
+		})
+	}
+}
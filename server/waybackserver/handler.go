@@ -0,0 +1,246 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package waybackserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"github.com/nlnwa/gowarcserver/server/mementoserver"
+	"github.com/nlnwa/gowarcserver/timestamp"
+	whatwgUrl "github.com/nlnwa/whatwg-url/url"
+	"github.com/rs/zerolog/log"
+)
+
+type Config struct {
+	// ReplayUrl is the template of replay URLs where "{timestamp}" and "{url}"
+	// are replaced by the timestamp and url of a capture. If empty, captures
+	// are replayed from WebPath.
+	ReplayUrl string
+	// WebPath is the path of the endpoint that captures are replayed from.
+	WebPath string
+	// Limits are the query limits by endpoint.
+	Limits api.QueryLimits
+}
+
+type Handler struct {
+	CdxAPI index.CdxAPI
+	Config *Config
+}
+
+type snapshot struct {
+	Status    string `json:"status"`
+	Available bool   `json:"available"`
+	Url       string `json:"url"`
+	Timestamp string `json:"timestamp"`
+}
+
+type archivedSnapshots struct {
+	Closest *snapshot `json:"closest,omitempty"`
+}
+
+type availability struct {
+	Url               string            `json:"url"`
+	Timestamp         string            `json:"timestamp,omitempty"`
+	ArchivedSnapshots archivedSnapshots `json:"archived_snapshots"`
+}
+
+// errInvalidLookup is the error of lookups of invalid urls or timestamps.
+var errInvalidLookup = errors.New("invalid lookup")
+
+// parseTimestamp parses a timestamp of 1 to 14 digits by padding it to a
+// valid CDX timestamp. An empty timestamp is the current time.
+func parseTimestamp(ts string) (string, error) {
+	if ts == "" {
+		return timestamp.TimeTo14(time.Now()), nil
+	}
+	if len(ts) > 14 || strings.Trim(ts, "0123456789") != "" {
+		return "", fmt.Errorf("invalid timestamp: %s", ts)
+	}
+	if len(ts) < 4 {
+		return "", fmt.Errorf("invalid timestamp: %s", ts)
+	}
+	// pad month and day with 01 and time with 0
+	padded := ts + "0101000000"[max(len(ts)-4, 0):]
+	if _, err := timestamp.Parse(padded); err != nil {
+		return "", fmt.Errorf("invalid timestamp: %s", ts)
+	}
+	return padded, nil
+}
+
+// replayUrl returns the replay URL of the capture of uri at ts.
+func (h Handler) replayUrl(r *http.Request, ts time.Time, uri string) string {
+	if h.Config.ReplayUrl == "" {
+		return mementoserver.MementoUrl(mementoserver.BaseUrl(r), h.Config.WebPath, ts, uri)
+	}
+	return strings.NewReplacer("{timestamp}", timestamp.TimeTo14(ts), "{url}", uri).Replace(h.Config.ReplayUrl)
+}
+
+// lookup returns the availability of the capture of uri closest to ts.
+func (h Handler) lookup(ctx context.Context, r *http.Request, uri string, ts string) (*availability, error) {
+	result := &availability{Url: uri, Timestamp: ts}
+
+	closest, err := parseTimestamp(ts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidLookup, err)
+	}
+	u := uri
+	if !api.SchemeRegExp.MatchString(u) {
+		u = "http://" + u
+	}
+	parsed, err := whatwgUrl.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse url: %w", errInvalidLookup, err)
+	}
+	req := api.ClosestRequest(closest, parsed)
+	// match captures regardless of scheme since urls are often given without one
+	req.SetMatchType(index.MatchTypeExact)
+
+	ctx, cancel := h.Config.Limits.Get("wayback-available").Apply(ctx, req)
+	defer cancel()
+
+	response := make(chan index.CdxResponse)
+	if err := h.CdxAPI.Search(ctx, req, response); err != nil {
+		return nil, err
+	}
+	var searchErr error
+	for res := range response {
+		if err := res.GetError(); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Warn().Err(err).Msg("failed result")
+			}
+			if searchErr == nil {
+				searchErr = err
+			}
+			continue
+		}
+		// the first result is the closest, the rest are drained
+		if result.ArchivedSnapshots.Closest != nil {
+			continue
+		}
+		cdx := res.GetCdx()
+		t := cdx.GetSts().AsTime()
+		status := ""
+		if cdx.GetHsc() != 0 {
+			status = strconv.Itoa(int(cdx.GetHsc()))
+		}
+		result.ArchivedSnapshots.Closest = &snapshot{
+			Status:    status,
+			Available: true,
+			Url:       h.replayUrl(r, t, cdx.GetUri()),
+			Timestamp: timestamp.TimeTo14(t),
+		}
+	}
+	// no snapshot from a failed search does not mean that the url is not archived
+	if searchErr != nil && result.ArchivedSnapshots.Closest == nil {
+		return nil, searchErr
+	}
+	return result, nil
+}
+
+// lookupError writes the error of a failed lookup with message.
+//
+// Lookups that failed by exceeding the query limits are unavailable and have the
+// Truncated header set to the reason.
+func lookupError(w http.ResponseWriter, message string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, errInvalidLookup) {
+		status = http.StatusBadRequest
+	} else if truncated := api.TruncatedBy(err); truncated != "" {
+		w.Header().Set(api.HeaderTruncated, truncated)
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, message, status)
+}
+
+// available writes the capture closest to the timestamp of a url.
+func (h Handler) available(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("url")
+	if uri == "" {
+		http.Error(w, "missing url", http.StatusBadRequest)
+		return
+	}
+	result, err := h.lookup(r.Context(), r, uri, r.URL.Query().Get("timestamp"))
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err != nil {
+		lookupError(w, err.Error(), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Warn().Err(err).Msg("failed to write availability")
+	}
+}
+
+// availableBatch writes the captures closest to the timestamps of the urls of a form.
+//
+// The form has one or more url values and either no timestamp, one timestamp
+// for all urls or one timestamp for each url. The batch fails with the first
+// lookup that fails.
+func (h Handler) availableBatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	urls := r.PostForm["url"]
+	timestamps := r.PostForm["timestamp"]
+	if len(urls) == 0 {
+		http.Error(w, "missing url", http.StatusBadRequest)
+		return
+	}
+	if len(timestamps) > 1 && len(timestamps) != len(urls) {
+		http.Error(w, "expected one timestamp or one timestamp for each url", http.StatusBadRequest)
+		return
+	}
+
+	results := struct {
+		Results []*availability `json:"results"`
+	}{}
+	for i, uri := range urls {
+		var ts string
+		switch len(timestamps) {
+		case 0:
+		case 1:
+			ts = timestamps[0]
+		default:
+			ts = timestamps[i]
+		}
+		result, err := h.lookup(r.Context(), r, uri, ts)
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			lookupError(w, fmt.Sprintf("%s: %v", uri, err), err)
+			return
+		}
+		results.Results = append(results.Results, result)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		log.Warn().Err(err).Msg("failed to write availability")
+	}
+}
@@ -0,0 +1,199 @@
+package waybackserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlnwa/gowarcserver/index"
+	"github.com/nlnwa/gowarcserver/index/indextest"
+	"github.com/nlnwa/gowarcserver/server/api"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestServer(replayUrl string) *httprouter.Router {
+	router := httprouter.New()
+	Register(Handler{
+		CdxAPI: indextest.CdxAPI{
+			{Uri: "https://example.com/", Srt: "response", Hsc: 200, Sts: timestamppb.New(time.Date(2020, time.April, 1, 22, 22, 0, 0, time.UTC))},
+			{Uri: "http://example.com/", Srt: "response", Hsc: 301, Sts: timestamppb.New(time.Date(2013, time.September, 19, 4, 46, 12, 0, time.UTC))},
+		},
+		Config: &Config{ReplayUrl: replayUrl, WebPath: "/warcserver/web"},
+	}, router, func(h http.Handler) http.Handler { return h }, "")
+	return router
+}
+
+func TestAvailable(t *testing.T) {
+	tests := []struct {
+		query     string
+		replayUrl string
+		status    int
+		want      string
+	}{
+		{
+			query:  "url=example.com/&timestamp=2013",
+			status: http.StatusOK,
+			want:   `{"url":"example.com/","timestamp":"2013","archived_snapshots":{"closest":{"status":"301","available":true,"url":"http://localhost/warcserver/web/20130919044612id_/http://example.com/","timestamp":"20130919044612"}}}`,
+		},
+		{
+			query:     "url=example.com/",
+			replayUrl: "https://replay.example.org/{timestamp}/{url}",
+			status:    http.StatusOK,
+			want:      `{"url":"example.com/","archived_snapshots":{"closest":{"status":"200","available":true,"url":"https://replay.example.org/20200401222200/https://example.com/","timestamp":"20200401222200"}}}`,
+		},
+		{
+			query:  "url=example.org/",
+			status: http.StatusOK,
+			want:   `{"url":"example.org/","archived_snapshots":{}}`,
+		},
+		{
+			query:  "url=example.com/&timestamp=20x",
+			status: http.StatusBadRequest,
+		},
+		{
+			query:  "timestamp=2013",
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newTestServer(test.replayUrl).ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/wayback/available?"+test.query, nil))
+			if rec.Code != test.status {
+				t.Fatalf("got status %d, want %d", rec.Code, test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAvailableBatch(t *testing.T) {
+	form := url.Values{
+		"url":       {"example.com/", "example.org/"},
+		"timestamp": {"2013"},
+	}
+	req := httptest.NewRequest("POST", "http://localhost/wayback/available", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	newTestServer("").ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got struct {
+		Results []availability `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(got.Results))
+	}
+	if got.Results[0].ArchivedSnapshots.Closest == nil || got.Results[0].ArchivedSnapshots.Closest.Timestamp != "20130919044612" {
+		t.Errorf("got %+v, want closest 20130919044612", got.Results[0])
+	}
+	if got.Results[1].ArchivedSnapshots.Closest != nil {
+		t.Errorf("got %+v, want no closest", got.Results[1])
+	}
+}
+
+// failingCdxAPI fails every search.
+type failingCdxAPI struct{}
+
+func (failingCdxAPI) Search(context.Context, index.Request, chan<- index.CdxResponse) error {
+	return errors.New("index unavailable")
+}
+
+func TestAvailableLookupFailure(t *testing.T) {
+	router := httprouter.New()
+	Register(Handler{CdxAPI: failingCdxAPI{}, Config: &Config{}}, router, func(h http.Handler) http.Handler { return h }, "")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/wayback/available?url=example.com/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestAvailableResultFailure(t *testing.T) {
+	cdxAPI := indextest.CdxAPI{
+		{Uri: "http://example.com/", Srt: "response", Hsc: 200, Sts: timestamppb.New(time.Date(2013, time.September, 19, 4, 46, 12, 0, time.UTC))},
+	}
+	tests := []struct {
+		name      string
+		url       string
+		after     int
+		err       error
+		status    int
+		truncated string
+	}{
+		{name: "timeout", url: "example.org/", err: context.DeadlineExceeded, status: http.StatusServiceUnavailable, truncated: "timeout"},
+		{name: "max keys", url: "example.com/", err: index.MaxKeysExceededError, status: http.StatusServiceUnavailable, truncated: "max-keys"},
+		{name: "index error", url: "example.org/", err: errors.New("index unavailable"), status: http.StatusInternalServerError},
+		{name: "after closest", url: "example.com/", after: 1, err: context.DeadlineExceeded, status: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := Handler{
+				CdxAPI: indextest.FailingCdxAPI{CdxAPI: cdxAPI, After: test.after, Err: test.err},
+				Config: &Config{},
+			}
+			router := httprouter.New()
+			Register(handler, router, func(h http.Handler) http.Handler { return h }, "")
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost/wayback/available?url="+test.url, nil))
+			if rec.Code != test.status {
+				t.Fatalf("got status %d, want %d", rec.Code, test.status)
+			}
+			if got := rec.Header().Get(api.HeaderTruncated); got != test.truncated {
+				t.Errorf("got truncated %q, want %q", got, test.truncated)
+			}
+
+			form := url.Values{"url": {test.url}}
+			req := httptest.NewRequest("POST", "http://localhost/wayback/available", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Fatalf("got batch status %d, want %d", rec.Code, test.status)
+			}
+			if got := rec.Header().Get(api.HeaderTruncated); got != test.truncated {
+				t.Errorf("got batch truncated %q, want %q", got, test.truncated)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := map[string]string{
+		"2013":           "20130101000000",
+		"201309":         "20130901000000",
+		"20130919":       "20130919000000",
+		"20130919044612": "20130919044612",
+	}
+	for ts, want := range tests {
+		got, err := parseTimestamp(ts)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", ts, err)
+		} else if got != want {
+			t.Errorf("%s: got %s, want %s", ts, got, want)
+		}
+	}
+	for _, ts := range []string{"201", "2013x", "201313", "201301019999999"} {
+		if _, err := parseTimestamp(ts); err == nil {
+			t.Errorf("%s: expected error", ts)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+/*
+ * Copyright 2025 National Library of Norway.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *       http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package waybackserver
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Register registers the Internet Archive Wayback compatible endpoints.
+func Register(h Handler, r *httprouter.Router, mw func(http.Handler) http.Handler, pathPrefix string) {
+	// https://archive.org/help/wayback_api.php
+	r.Handler("GET", pathPrefix+"/wayback/available", mw(http.HandlerFunc(h.available)))
+	r.Handler("POST", pathPrefix+"/wayback/available", mw(http.HandlerFunc(h.availableBatch)))
+	// https://github.com/internetarchive/wayback/tree/master/wayback-cdx-server
+	r.Handler("GET", pathPrefix+"/cdx/search/cdx", mw(http.HandlerFunc(h.cdx)))
+}
@@ -37,6 +37,14 @@ func UrlToSsurt(u *url.Url) string {
 	// TODO normalize search params, e.g. remove session tokens
 	sb := new(strings.Builder)
 
+	// dns URIs (e.g. dns:www.example.com) have the hostname as path
+	if u.Scheme() == "dns" && u.Hostname() == "" && u.Pathname() != "" {
+		writeSurtHost(sb, u.Pathname())
+		sb.WriteString("//")
+		writeScheme(sb, u)
+		return sb.String()
+	}
+
 	writeHostName(sb, u)
 	writeScheme(sb, u)
 	sb.WriteString(u.Pathname())
@@ -59,6 +67,11 @@ func writeHostName(sb *strings.Builder, u *url.Url) {
 	if hostname == "" {
 		return
 	}
+	writeSurtHost(sb, hostname)
+	sb.WriteString("//")
+}
+
+func writeSurtHost(sb *strings.Builder, hostname string) {
 	if hostname[0] == '[' {
 		sb.WriteString(hostname)
 	} else if net.ParseIP(hostname).To4() != nil {
@@ -70,8 +83,6 @@ func writeHostName(sb *strings.Builder, u *url.Url) {
 			sb.WriteByte(',')
 		}
 	}
-
-	sb.WriteString("//")
 }
 
 func writeScheme(sb *strings.Builder, u *url.Url) {
@@ -93,3 +104,39 @@ func writeScheme(sb *strings.Builder, u *url.Url) {
 
 	sb.WriteByte(':')
 }
+
+// SplitSSURT splits a ssurt (e.g. "com,example,//8080:http@user:password:/path")
+// into surt host, port scheme and userinfo, and path.
+func SplitSSURT(ssurt string) (surtHost string, portSchemeUserInfo string, path string) {
+	i := strings.Index(ssurt, "//")
+	if i == -1 {
+		return ssurt, "", ""
+	}
+	surtHost = ssurt[:i]
+
+	if len(ssurt) < i+2 {
+		return surtHost, "", ""
+	}
+	if len(ssurt) == i+2 {
+		return surtHost, "", "/"
+	}
+
+	j := strings.Index(ssurt[i+2:], "/")
+	if j == -1 {
+		return surtHost, ssurt[i+2:], ""
+	}
+	portSchemeUserInfo = ssurt[i+2 : i+2+j]
+
+	path = ssurt[i+2+j:]
+
+	return
+}
+
+// SplitPortSchemeUserInfo splits the port, scheme and userinfo part of a ssurt
+// (e.g. "8080:http@user:password:") into port, scheme and userinfo.
+func SplitPortSchemeUserInfo(portSchemeUserInfo string) (port string, scheme string, userInfo string) {
+	port, schemeUserInfo, _ := strings.Cut(portSchemeUserInfo, ":")
+	schemeUserInfo = strings.TrimSuffix(schemeUserInfo, ":")
+	scheme, userInfo, _ = strings.Cut(schemeUserInfo, "@")
+	return
+}
@@ -43,6 +43,7 @@ func TestSsurtS(t *testing.T) {
 		{"scheme://foo.example.org:81/path?query#frag", "org,example,foo,//81:scheme:/path?query#frag", false},
 		{"scheme://foo.example.org/path?query#frag", "org,example,foo,//:scheme:/path?query#frag", false},
 		{"scheme://81.foo.example.org/path?query#frag", "org,example,foo,81,//:scheme:/path?query#frag", false},
+		{"dns:www.example.com", "com,example,www,//:dns:", false},
 		// TODO the url parser does not by default provide access to part after scheme when there is no authority
 		// {"screenshot:http://example.com/", "com,example,//screenshot:", false},
 	}
@@ -61,3 +62,70 @@ func TestSsurtS(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitSurt(t *testing.T) {
+	tests := []struct {
+		ssurt             string
+		host              string
+		schemeAndUserinfo string
+		path              string
+	}{
+		{
+			"no,nb,//8080:http@user:password:/path?query#fragment",
+			"no,nb,",
+			"8080:http@user:password:",
+			"/path?query#fragment",
+		},
+		{
+			"no,nb,//:http@user:password:/path?query#fragment",
+			"no,nb,",
+			":http@user:password:",
+			"/path?query#fragment",
+		},
+		{
+			"no,nb,//:http:/path?query#fragment",
+			"no,nb,",
+			":http:",
+			"/path?query#fragment",
+		},
+		{
+			"no,nb,//:http:/path",
+			"no,nb,",
+			":http:",
+			"/path",
+		},
+		{
+			"no,nb,//:http:/",
+			"no,nb,",
+			":http:",
+			"/",
+		},
+		{
+			"no,nb,",
+			"no,nb,",
+			"",
+			"",
+		},
+		{
+			"no,nb,//",
+			"no,nb,",
+			"",
+			"/",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ssurt, func(t *testing.T) {
+			host, schemeAndUserinfo, path := SplitSSURT(test.ssurt)
+			if host != test.host {
+				t.Errorf("want '%s', got '%s'", test.host, host)
+			}
+			if schemeAndUserinfo != test.schemeAndUserinfo {
+				t.Errorf("want '%s', got '%s'", test.schemeAndUserinfo, schemeAndUserinfo)
+			}
+			if path != test.path {
+				t.Errorf("want '%s', got '%s'", test.path, path)
+			}
+		})
+	}
+}
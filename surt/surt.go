@@ -49,3 +49,16 @@ func SurtS(u string, includeScheme bool) (string, error) {
 	}
 	return SurtU(u2, includeScheme)
 }
+
+// UrlKey returns the SURT of u in the form of the urlkey of the CDX server API of the
+// Internet Archive, i.e. lower case without the scheme and the www prefix of the host,
+// e.g. "com,example)/path?query".
+func UrlKey(u string) (string, error) {
+	s, err := SurtS(u, false)
+	if err != nil {
+		return "", err
+	}
+	host, rest, _ := strings.Cut(strings.TrimPrefix(s, "("), ")")
+	host = strings.TrimSuffix(strings.TrimSuffix(host, ","), ",www")
+	return strings.ToLower(host + ")" + rest), nil
+}
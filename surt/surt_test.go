@@ -54,3 +54,25 @@ func TestSurtS(t *testing.T) {
 		})
 	}
 }
+
+func TestUrlKey(t *testing.T) {
+	tests := []struct {
+		u    string
+		want string
+	}{
+		{"http://www.example.com", "com,example)/"},
+		{"https://example.com/Foo/bar?b=2&a=1#fragment", "com,example)/foo/bar?a=1&b=2"},
+		{"http://www.www.example.com/", "com,example,www)/"},
+		{"http://127.0.0.1/foo", "1,0,0,127)/foo"},
+	}
+	for _, tt := range tests {
+		got, err := UrlKey(tt.u)
+		if err != nil {
+			t.Errorf("UrlKey(%q): unexpected error: %v", tt.u, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("UrlKey(%q): got %q, want %q", tt.u, got, tt.want)
+		}
+	}
+}